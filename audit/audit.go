@@ -0,0 +1,215 @@
+// Package audit records authentication and profile changes of users
+// in an append-only store served by the ListAuditEvents RPC.
+//
+// Events are kept as evidence of who did what to an account. When a user
+// is erased the EraseUser handler calls Store.Redact, which drops the
+// personal data of the events but keeps their type, actor and time.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid audit cursor")
+
+// Store keeps audit events. It has no update or delete on purpose,
+// Redact is the only change allowed to a stored event.
+type Store interface {
+	Append(ctx context.Context, event *models.AuditEvent) error
+	// List returns matching events oldest first
+	List(ctx context.Context, filter models.AuditFilter, cursor string) (*models.AuditPage, error)
+	// Redact clears the changed values and the detail of every event of the user
+	Redact(ctx context.Context, userUUID uuid.UUID) error
+}
+
+// Recorder builds audit events and appends them to the store
+type Recorder struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewRecorder is
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store, now: time.Now}
+}
+
+// SignUp records a sign up attempt
+func (r *Recorder) SignUp(ctx context.Context, userUUID uuid.UUID, err error) error {
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditSignUp,
+		UserUUID:  userUUID,
+		ActorUUID: userUUID,
+		Success:   err == nil,
+		Detail:    errDetail(err),
+	})
+}
+
+// SignIn records a successful or failed sign in, userUUID is nil for unknown emails
+func (r *Recorder) SignIn(ctx context.Context, userUUID uuid.UUID, err error) error {
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditSignIn,
+		UserUUID:  userUUID,
+		ActorUUID: userUUID,
+		Success:   err == nil,
+		Detail:    errDetail(err),
+	})
+}
+
+// UserUpdated records the fields changed by actor
+func (r *Recorder) UserUpdated(ctx context.Context, actorUUID uuid.UUID, before, after models.User) error {
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditUserUpdated,
+		UserUUID:  after.UserUUID,
+		ActorUUID: actorUUID,
+		Success:   true,
+		Changes:   models.Diff(before, after),
+	})
+}
+
+// TokenRevoked records a revoked token of the user
+func (r *Recorder) TokenRevoked(ctx context.Context, actorUUID, userUUID uuid.UUID, detail string) error {
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditTokenRevoked,
+		UserUUID:  userUUID,
+		ActorUUID: actorUUID,
+		Success:   true,
+		Detail:    detail,
+	})
+}
+
+// AdminAction records an action of an admin on the user, e.g. "deactivate: spam"
+func (r *Recorder) AdminAction(ctx context.Context, adminUUID, userUUID uuid.UUID, action string) error {
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditAdminAction,
+		UserUUID:  userUUID,
+		ActorUUID: adminUUID,
+		Success:   true,
+		Detail:    action,
+	})
+}
+
+// adminCall records a call of an admin method, failed calls included
+func (r *Recorder) adminCall(ctx context.Context, adminUUID, userUUID uuid.UUID, action string, err error) error {
+	if err != nil {
+		action += ": " + err.Error()
+	}
+	return r.record(ctx, &models.AuditEvent{
+		Type:      models.AuditAdminAction,
+		UserUUID:  userUUID,
+		ActorUUID: adminUUID,
+		Success:   err == nil,
+		Detail:    action,
+	})
+}
+
+func (r *Recorder) record(ctx context.Context, event *models.AuditEvent) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("audit event uuid: %w", err)
+	}
+	event.EventUUID = id
+	event.OccurredAt = r.now()
+	if err := r.store.Append(ctx, event); err != nil {
+		return fmt.Errorf("append audit event: %w", err)
+	}
+	return nil
+}
+
+func errDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// MemoryStore is a Store kept in memory, for tests and single instance deployments
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []*models.AuditEvent
+}
+
+// NewMemoryStore is
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append is
+func (s *MemoryStore) Append(_ context.Context, event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, copyEvent(event))
+	return nil
+}
+
+// List is, the cursor is the position in the log to continue from
+func (s *MemoryStore) List(_ context.Context, filter models.AuditFilter, cursor string) (*models.AuditPage, error) {
+	start := 0
+	if cursor != "" {
+		var err error
+		if start, err = strconv.Atoi(cursor); err != nil || start < 0 {
+			return nil, ErrInvalidCursor
+		}
+	}
+	size := filter.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	size = min(size, maxPageSize)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	page := &models.AuditPage{}
+	for i := start; i < len(s.events); i++ {
+		if !filter.Matches(s.events[i]) {
+			continue
+		}
+		if len(page.Events) == size {
+			page.NextCursor = strconv.Itoa(i)
+			break
+		}
+		page.Events = append(page.Events, copyEvent(s.events[i]))
+	}
+	return page, nil
+}
+
+// Redact is
+func (s *MemoryStore) Redact(_ context.Context, userUUID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.events {
+		if e.UserUUID != userUUID {
+			continue
+		}
+		redacted := copyEvent(e)
+		redacted.Detail = ""
+		for j := range redacted.Changes {
+			redacted.Changes[j].Before = ""
+			redacted.Changes[j].After = ""
+		}
+		// events already listed keep their own copy
+		s.events[i] = redacted
+	}
+	return nil
+}
+
+func copyEvent(event *models.AuditEvent) *models.AuditEvent {
+	e := *event
+	e.Changes = append([]models.FieldChange(nil), event.Changes...)
+	return &e
+}
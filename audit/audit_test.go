@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/garden-raccoon/user-pkg/auth"
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMemoryStoreListReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	user := models.User{UserUUID: uuid.Must(uuid.NewV4()), Email: "old@example.com"}
+	changed := user
+	changed.Email = "new@example.com"
+	if err := NewRecorder(store).UserUpdated(ctx, user.UserUUID, user, changed); err != nil {
+		t.Fatalf("UserUpdated: %v", err)
+	}
+
+	page, err := store.List(ctx, models.AuditFilter{}, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	page.Events[0].Changes[0].After = "tampered"
+
+	page, err = store.List(ctx, models.AuditFilter{}, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := page.Events[0].Changes[0].After; got != "new@example.com" {
+		t.Fatalf("stored change = %q, want the original value", got)
+	}
+}
+
+func TestMemoryStoreRedact(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rec := NewRecorder(store)
+	erased, other := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	before := models.User{UserUUID: erased, Email: "old@example.com"}
+	after := before
+	after.Email = "new@example.com"
+	if err := rec.UserUpdated(ctx, erased, before, after); err != nil {
+		t.Fatalf("UserUpdated: %v", err)
+	}
+	if err := rec.SignIn(ctx, other, errors.New("wrong password for other@example.com")); err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	listed, err := store.List(ctx, models.AuditFilter{UserUUID: erased}, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if err := store.Redact(ctx, erased); err != nil {
+		t.Fatalf("Redact: %v", err)
+	}
+
+	page, err := store.List(ctx, models.AuditFilter{}, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Events) != 2 {
+		t.Fatalf("got %d events, redaction must not delete", len(page.Events))
+	}
+	got := page.Events[0]
+	if got.Type != models.AuditUserUpdated || got.ActorUUID != erased {
+		t.Fatalf("redacted event lost its evidence: %+v", got)
+	}
+	if c := got.Changes[0]; c.Field == "" || c.Before != "" || c.After != "" {
+		t.Fatalf("change not redacted: %+v", c)
+	}
+	if page.Events[1].Detail == "" {
+		t.Fatal("event of another user was redacted")
+	}
+	if listed.Events[0].Changes[0].After != "new@example.com" {
+		t.Fatal("redact changed an event returned before")
+	}
+}
+
+func TestUnaryServerInterceptorRecordsAdminCalls(t *testing.T) {
+	const eraseMethod = "/service.UserService/EraseUser"
+	store := NewMemoryStore()
+	admin, target := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	interceptor := UnaryServerInterceptor(NewRecorder(store), InterceptorConfig{Methods: []string{eraseMethod}})
+	ctx := auth.ContextWithUser(context.Background(), &models.User{UserUUID: admin})
+
+	call := func(method string, handlerErr error) {
+		t.Helper()
+		req := &proto.EraseUserRequest{UserUuid: target.Bytes(), Reason: "requested"}
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, any) (any, error) { return nil, handlerErr })
+		if !errors.Is(err, handlerErr) {
+			t.Fatalf("interceptor changed the handler error: %v", err)
+		}
+	}
+	call(eraseMethod, nil)
+	call(eraseMethod, status.Error(codes.NotFound, "user not found"))
+	call("/service.UserService/UserBy", nil)
+
+	page, err := store.List(context.Background(), models.AuditFilter{}, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Events) != 2 {
+		t.Fatalf("got %d events, want only the 2 admin calls", len(page.Events))
+	}
+	ok, failed := page.Events[0], page.Events[1]
+	if ok.Type != models.AuditAdminAction || ok.ActorUUID != admin || ok.UserUUID != target ||
+		!ok.Success || ok.Detail != "EraseUser: requested" {
+		t.Fatalf("unexpected event %+v", ok)
+	}
+	if failed.Success {
+		t.Fatalf("failed call recorded as success: %+v", failed)
+	}
+}
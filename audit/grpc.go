@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/garden-raccoon/user-pkg/auth"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+)
+
+// InterceptorConfig selects the admin methods recorded by UnaryServerInterceptor
+type InterceptorConfig struct {
+	// Methods are full method names, e.g. "/service.UserService/EraseUser"
+	Methods []string
+	// OnError is called when a call could not be recorded,
+	// the call itself has already run and keeps its result
+	OnError func(method string, err error)
+}
+
+// UnaryServerInterceptor records every call of the configured methods as an
+// admin action. It must run after the auth interceptor: the actor is
+// auth.UserFromContext and the user is the user_uuid of the request.
+func UnaryServerInterceptor(r *Recorder, cfg InterceptorConfig) grpc.UnaryServerInterceptor {
+	methods := make(map[string]bool, len(cfg.Methods))
+	for _, m := range cfg.Methods {
+		methods[m] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+		var actorUUID uuid.UUID
+		if actor := auth.UserFromContext(ctx); actor != nil {
+			actorUUID = actor.UserUUID
+		}
+		if recErr := r.adminCall(ctx, actorUUID, requestUser(req), action(info.FullMethod, req), err); recErr != nil && cfg.OnError != nil {
+			cfg.OnError(info.FullMethod, recErr)
+		}
+		return resp, err
+	}
+}
+
+// requestUser is the user_uuid of the request or uuid.Nil
+func requestUser(req any) uuid.UUID {
+	if r, ok := req.(interface{ GetUserUuid() []byte }); ok {
+		return uuid.FromBytesOrNil(r.GetUserUuid())
+	}
+	return uuid.Nil
+}
+
+// action is the method name followed by the reason given in the request, if any
+func action(fullMethod string, req any) string {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if r, ok := req.(interface{ GetReason() string }); ok && r.GetReason() != "" {
+		return name + ": " + r.GetReason()
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+// Package auth authenticates inbound requests of downstream services with
+// user-service tokens. The verified *models.User is stored in the request
+// context and read back with UserFromContext.
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Verifier validates a token. user.IUserAPI and its cache verify online
+// with CheckAuth, an offline verifier can check the signature locally and
+// should wrap ErrInvalidToken for rejected tokens.
+type Verifier interface {
+	CheckAuth(token []byte) (*models.User, error)
+}
+
+type userKey struct{}
+
+// ContextWithUser returns a copy of ctx carrying the user
+func ContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user or nil
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey{}).(*models.User)
+	return user
+}
+
+// verify maps verifier failures to a gRPC status, so the same codes are
+// used by the gRPC interceptors and translated to HTTP by the middleware
+func verify(v Verifier, token string) (*models.User, error) {
+	user, err := v.CheckAuth([]byte(token))
+	if err == nil {
+		if user == nil {
+			return nil, status.Error(codes.Unauthenticated, ErrInvalidToken.Error())
+		}
+		return user, nil
+	}
+
+	if se, ok := models.AccountStatusErrorFromError(err); ok {
+		return nil, status.Error(codes.PermissionDenied, se.Error())
+	}
+	if errors.Is(err, ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, ErrInvalidToken.Error())
+	}
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.InvalidArgument, codes.NotFound, codes.PermissionDenied:
+		return nil, status.Error(codes.Unauthenticated, ErrInvalidToken.Error())
+	}
+	// the user service is down, throttled or the circuit is open
+	return nil, status.Error(codes.Unavailable, "token verification unavailable")
+}
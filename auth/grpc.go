@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/garden-raccoon/user-pkg/internal/bearer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key of the bearer token
+const authorizationKey = "authorization"
+
+// Option configures the interceptors
+type Option func(o *options)
+
+type options struct {
+	public map[string]bool
+	skip   map[string]bool
+}
+
+// WithPublicMethods makes the token optional for the full method names,
+// e.g. "/shop.ShopService/ListShops", a valid token still sets the user
+func WithPublicMethods(methods ...string) Option {
+	return func(o *options) {
+		for _, m := range methods {
+			o.public[m] = true
+		}
+	}
+}
+
+// WithSkipMethods disables authentication for the full method names,
+// e.g. health checks and reflection
+func WithSkipMethods(methods ...string) Option {
+	return func(o *options) {
+		for _, m := range methods {
+			o.skip[m] = true
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{public: make(map[string]bool), skip: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// UnaryServerInterceptor authenticates unary calls with v
+func UnaryServerInterceptor(v Verifier, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := o.authenticate(ctx, v, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming calls with v
+func StreamServerInterceptor(v Verifier, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := o.authenticate(ss.Context(), v, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (o *options) authenticate(ctx context.Context, v Verifier, method string) (context.Context, error) {
+	if o.skip[method] {
+		return ctx, nil
+	}
+
+	token, ok := tokenFromMetadata(ctx)
+	if !ok {
+		if o.public[method] {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, ErrMissingToken.Error())
+	}
+
+	user, err := verify(v, token)
+	if err != nil {
+		if o.public[method] && status.Code(err) == codes.Unauthenticated {
+			return ctx, nil
+		}
+		return nil, err
+	}
+	return ContextWithUser(ctx, user), nil
+}
+
+func tokenFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, v := range md.Get(authorizationKey) {
+		if token, ok := bearer.Token(v); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// authenticatedStream replaces the stream context with the one carrying the user
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
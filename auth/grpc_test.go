@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	publicMethod  = "/shop.ShopService/ListShops"
+	skippedMethod = "/grpc.health.v1.Health/Check"
+	privateMethod = "/shop.ShopService/CreateShop"
+)
+
+var testUser = &models.User{UserUUID: uuid.Must(uuid.NewV4()), Email: "user@example.com"}
+
+// fakeVerifier answers CheckAuth by token
+type fakeVerifier map[string]struct {
+	user *models.User
+	err  error
+}
+
+func (v fakeVerifier) CheckAuth(token []byte) (*models.User, error) {
+	r, ok := v[string(token)]
+	if !ok {
+		return nil, fmt.Errorf("check token: %w", ErrInvalidToken)
+	}
+	return r.user, r.err
+}
+
+var verifier = fakeVerifier{
+	"valid":       {user: testUser},
+	"expired":     {err: fmt.Errorf("token expired: %w", ErrInvalidToken)},
+	"deactivated": {err: &models.AccountStatusError{Status: models.StatusDeactivated}},
+	"remote-deleted": {err: fmt.Errorf("checkAuth api request: %w",
+		(&models.AccountStatusError{Status: models.StatusDeleted}).GRPCStatus().Err())},
+	"no-user":         {},
+	"unauthenticated": {err: status.Error(codes.Unauthenticated, "bad signature")},
+	"malformed":       {err: status.Error(codes.InvalidArgument, "malformed token")},
+	"unknown-user":    {err: status.Error(codes.NotFound, "user not found")},
+	"denied":          {err: status.Error(codes.PermissionDenied, "denied")},
+	"unavailable":     {err: status.Error(codes.Unavailable, "connection refused")},
+	"circuit-open":    {err: errors.New("/service.UserService/CheckAuth: circuit breaker is open")},
+	"internal":        {err: status.Error(codes.Internal, "boom")},
+}
+
+func incoming(token string) context.Context {
+	if token == "" {
+		return context.Background()
+	}
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationKey, "Bearer "+token))
+}
+
+var authTests = []struct {
+	name     string
+	method   string
+	token    string
+	wantCode codes.Code
+	wantUser bool
+}{
+	{"valid token", privateMethod, "valid", codes.OK, true},
+	{"missing token", privateMethod, "", codes.Unauthenticated, false},
+	{"unknown token", privateMethod, "forged", codes.Unauthenticated, false},
+	{"expired token", privateMethod, "expired", codes.Unauthenticated, false},
+	{"no user", privateMethod, "no-user", codes.Unauthenticated, false},
+	{"inactive user", privateMethod, "deactivated", codes.PermissionDenied, false},
+	{"inactive user from the service", privateMethod, "remote-deleted", codes.PermissionDenied, false},
+	{"backend unauthenticated", privateMethod, "unauthenticated", codes.Unauthenticated, false},
+	{"backend invalid argument", privateMethod, "malformed", codes.Unauthenticated, false},
+	{"backend not found", privateMethod, "unknown-user", codes.Unauthenticated, false},
+	{"backend permission denied", privateMethod, "denied", codes.Unauthenticated, false},
+	{"backend unavailable", privateMethod, "unavailable", codes.Unavailable, false},
+	{"circuit open", privateMethod, "circuit-open", codes.Unavailable, false},
+	{"backend internal", privateMethod, "internal", codes.Unavailable, false},
+	{"public without token", publicMethod, "", codes.OK, false},
+	{"public with token", publicMethod, "valid", codes.OK, true},
+	{"public with invalid token", publicMethod, "expired", codes.OK, false},
+	{"public with inactive user", publicMethod, "deactivated", codes.PermissionDenied, false},
+	{"public with backend down", publicMethod, "unavailable", codes.Unavailable, false},
+	{"skipped without token", skippedMethod, "", codes.OK, false},
+	{"skipped ignores token", skippedMethod, "valid", codes.OK, false},
+}
+
+var authOptions = []Option{WithPublicMethods(publicMethod), WithSkipMethods(skippedMethod)}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(verifier, authOptions...)
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *models.User
+			called := false
+			_, err := interceptor(incoming(tt.token), nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(ctx context.Context, _ any) (any, error) {
+					called = true
+					got = UserFromContext(ctx)
+					return nil, nil
+				})
+			checkAuth(t, tt.wantCode, tt.wantUser, err, called, got)
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(verifier, authOptions...)
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *models.User
+			called := false
+			err := interceptor(nil, &fakeServerStream{ctx: incoming(tt.token)},
+				&grpc.StreamServerInfo{FullMethod: tt.method},
+				func(_ any, ss grpc.ServerStream) error {
+					called = true
+					got = UserFromContext(ss.Context())
+					return nil
+				})
+			checkAuth(t, tt.wantCode, tt.wantUser, err, called, got)
+		})
+	}
+}
+
+func checkAuth(t *testing.T, wantCode codes.Code, wantUser bool, err error, called bool, got *models.User) {
+	t.Helper()
+	if code := status.Code(err); code != wantCode {
+		t.Fatalf("code = %s, want %s: %v", code, wantCode, err)
+	}
+	if called != (wantCode == codes.OK) {
+		t.Fatalf("handler called = %v with code %s", called, wantCode)
+	}
+	if wantUser && got != testUser {
+		t.Fatalf("user in context = %v, want the verified user", got)
+	}
+	if !wantUser && got != nil {
+		t.Fatalf("user in context = %v, want none", got)
+	}
+}
+
+func TestTokenFromMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"bearer", []string{"Bearer abc"}, "abc"},
+		{"case insensitive scheme", []string{"bearer abc"}, "abc"},
+		{"basic only", []string{"Basic dXNlcjpwdw=="}, ""},
+		{"second value", []string{"Basic dXNlcjpwdw==", "Bearer abc"}, "abc"},
+		{"empty token", []string{"Bearer "}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			md.Append(authorizationKey, tt.values...)
+			got, ok := tokenFromMetadata(metadata.NewIncomingContext(context.Background(), md))
+			if got != tt.want || ok != (tt.want != "") {
+				t.Fatalf("tokenFromMetadata = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := tokenFromMetadata(context.Background()); ok {
+		t.Fatal("token found without metadata")
+	}
+}
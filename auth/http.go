@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/garden-raccoon/user-pkg/internal/bearer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Problem is an RFC 7807 problem details body
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HTTPOption configures Middleware
+type HTTPOption func(o *httpOptions)
+
+type httpOptions struct {
+	cookie string
+	skip   map[string]bool
+}
+
+// WithCookie reads the token from the cookie when there is no Authorization header
+func WithCookie(name string) HTTPOption {
+	return func(o *httpOptions) {
+		o.cookie = name
+	}
+}
+
+// WithSkipPaths disables authentication for the exact URL paths,
+// e.g. "/healthz" and "/metrics"
+func WithSkipPaths(paths ...string) HTTPOption {
+	return func(o *httpOptions) {
+		for _, p := range paths {
+			o.skip[p] = true
+		}
+	}
+}
+
+// Middleware authenticates requests with an "Authorization: Bearer" header
+// or the configured cookie and stores the user in the request context.
+// Rejected requests get a 401, 403 or 503 problem+json response.
+func Middleware(v Verifier, opts ...HTTPOption) func(http.Handler) http.Handler {
+	o := &httpOptions{skip: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := o.token(r)
+			if !ok {
+				writeProblem(w, http.StatusUnauthorized, ErrMissingToken.Error())
+				return
+			}
+
+			user, err := verify(v, token)
+			if err != nil {
+				writeProblem(w, httpStatus(status.Code(err)), status.Convert(err).Message())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), user)))
+		})
+	}
+}
+
+func (o *httpOptions) token(r *http.Request) (string, bool) {
+	if token, ok := bearer.Token(r.Header.Get("Authorization")); ok {
+		return token, true
+	}
+	if o.cookie == "" {
+		return "", false
+	}
+	c, err := r.Cookie(o.cookie)
+	if err != nil || c.Value == "" {
+		return "", false
+	}
+	return c.Value, true
+}
+
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeProblem(w http.ResponseWriter, code int, detail string) {
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/garden-raccoon/user-pkg/models"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		cookie     string
+		wantStatus int
+		wantUser   bool
+	}{
+		{"bearer header", "/shops", "Bearer valid", "", http.StatusOK, true},
+		{"cookie", "/shops", "", "valid", http.StatusOK, true},
+		{"header wins over cookie", "/shops", "Bearer expired", "valid", http.StatusUnauthorized, false},
+		{"non bearer header falls back to cookie", "/shops", "Basic dXNlcjpwdw==", "valid", http.StatusOK, true},
+		{"missing token", "/shops", "", "", http.StatusUnauthorized, false},
+		{"invalid token", "/shops", "Bearer forged", "", http.StatusUnauthorized, false},
+		{"inactive user", "/shops", "Bearer deactivated", "", http.StatusForbidden, false},
+		{"backend unavailable", "/shops", "Bearer unavailable", "", http.StatusServiceUnavailable, false},
+		{"skipped path", "/healthz", "", "", http.StatusOK, false},
+		{"skipped path ignores token", "/healthz", "Bearer valid", "", http.StatusOK, false},
+		{"skip is exact", "/healthz/deep", "", "", http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *models.User
+			called := false
+			h := Middleware(verifier, WithCookie("session"), WithSkipPaths("/healthz"))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					called = true
+					got = UserFromContext(r.Context())
+				}))
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Fatalf("handler called = %v with status %d", called, w.Code)
+			}
+			if tt.wantUser != (got == testUser) || (!tt.wantUser && got != nil) {
+				t.Fatalf("user in context = %v, want user %v", got, tt.wantUser)
+			}
+			if tt.wantStatus != http.StatusOK {
+				checkProblem(t, w, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWithoutCookie(t *testing.T) {
+	h := Middleware(verifier)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("handler called without a cookie option")
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "valid"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+}
+
+func checkProblem(t *testing.T, w *httptest.ResponseRecorder, code int) {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	challenge := w.Header().Get("WWW-Authenticate")
+	if (code == http.StatusUnauthorized) != (challenge == `Bearer realm="api"`) {
+		t.Fatalf("WWW-Authenticate = %q with status %d", challenge, code)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if p.Type != "about:blank" || p.Status != code || p.Title != http.StatusText(code) || p.Detail == "" {
+		t.Fatalf("problem = %+v", p)
+	}
+}
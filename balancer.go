@@ -0,0 +1,74 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+
+	// registers client side health checking used by healthCheckConfig
+	_ "google.golang.org/grpc/health"
+)
+
+// defaultServiceConfig balances over every resolved address and takes
+// addresses out of rotation while their health service is not serving.
+// It is used with WithStaticEndpoints, WithResolver and "dns:///host:port" targets.
+var defaultServiceConfig = fmt.Sprintf(`{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": %q}
+}`, healthService)
+
+// staticSchemeSeq makes the manual resolver scheme unique per client
+var staticSchemeSeq atomic.Uint64
+
+// WithStaticEndpoints balances calls over addr and the given addresses
+func WithStaticEndpoints(addrs ...string) Option {
+	return func(api *UsersAPI) {
+		api.endpoints = append(api.endpoints, addrs...)
+	}
+}
+
+// WithResolver registers a resolver for this client only, e.g. one backed
+// by a service registry. Pass New an address with the resolver scheme,
+// like "registry:///userapi".
+func WithResolver(b resolver.Builder) Option {
+	return func(api *UsersAPI) {
+		api.resolvers = append(api.resolvers, b)
+	}
+}
+
+// WithServiceConfig replaces the default round robin and health checking
+// service config, also for a single address. It is ignored if the resolver
+// provides one.
+func WithServiceConfig(config string) Option {
+	return func(api *UsersAPI) {
+		api.serviceConfig = config
+	}
+}
+
+// target returns the dial target for addr, with a static resolver when
+// several endpoints are configured. DNS is used with "dns:///host:port",
+// every resolved address is balanced and health checked.
+func (api *UsersAPI) target(addr string) string {
+	if len(api.endpoints) == 0 {
+		return addr
+	}
+
+	r := manual.NewBuilderWithScheme(fmt.Sprintf("userapi-static-%d", staticSchemeSeq.Add(1)))
+	state := resolver.State{}
+	for _, a := range append([]string{addr}, api.endpoints...) {
+		state.Endpoints = append(state.Endpoints, resolver.Endpoint{Addresses: []resolver.Address{{Addr: a}}})
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: a})
+	}
+	r.InitialState(state)
+	api.resolvers = append(api.resolvers, r)
+	return r.Scheme() + ":///" + healthService
+}
+
+// balanced reports whether target may resolve to several addresses,
+// those get the default service config
+func (api *UsersAPI) balanced(target string) bool {
+	return len(api.endpoints) > 0 || len(api.resolvers) > 0 || strings.HasPrefix(target, "dns:")
+}
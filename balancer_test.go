@@ -0,0 +1,114 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestStaticEndpointsRoundRobin(t *testing.T) {
+	servers := make([]*testServer, 3)
+	addrs := make([]string, 3)
+	for i := range servers {
+		servers[i] = &testServer{}
+		addrs[i], _ = startTestServer(t, servers[i])
+	}
+
+	api, err := NewClient(addrs[0], WithStaticEndpoints(addrs[1:]...), WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+			t.Fatalf("UserByUUID: %v", err)
+		}
+		if servers[0].calls.Load() > 0 && servers[1].calls.Load() > 0 && servers[2].calls.Load() > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("calls not spread over every endpoint: %d %d %d",
+				servers[0].calls.Load(), servers[1].calls.Load(), servers[2].calls.Load())
+		}
+	}
+}
+
+func TestStaticEndpointsSkipNotServing(t *testing.T) {
+	serving, down := &testServer{}, &testServer{}
+	servingAddr, _ := startTestServer(t, serving)
+	downAddr, hs := startTestServer(t, down)
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	api, err := NewClient(servingAddr, WithStaticEndpoints(downAddr), WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	for range 20 {
+		if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+			t.Fatalf("UserByUUID: %v", err)
+		}
+	}
+	if n := down.calls.Load(); n != 0 {
+		t.Fatalf("not serving endpoint got %d calls", n)
+	}
+	if n := serving.calls.Load(); n != 20 {
+		t.Fatalf("serving endpoint got %d calls, want 20", n)
+	}
+}
+
+// TestSingleAddressIgnoresHealth checks a client of one address is not
+// blocked by health checking it did not ask for
+func TestSingleAddressIgnoresHealth(t *testing.T) {
+	srv := &testServer{}
+	addr, hs := startTestServer(t, srv)
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	api, err := NewClient(addr, WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+}
+
+// TestDNSTargetChecksHealth checks a "dns:///" target is balanced with
+// health checking, unlike a plain address
+func TestDNSTargetChecksHealth(t *testing.T) {
+	srv := &testServer{}
+	addr, hs := startTestServer(t, srv)
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	api, err := NewClient("dns:///"+addr, WithTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err == nil {
+		t.Fatal("call reached an endpoint that is not serving")
+	}
+	if n := srv.calls.Load(); n != 0 {
+		t.Fatalf("not serving endpoint got %d calls", n)
+	}
+
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := api.UserByUUID(uuid.Must(uuid.NewV4()))
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("UserByUUID after the endpoint is serving: %v", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// batchChunkSize is the max number of uuids sent in one UsersByUUIDs request
+	batchChunkSize = 100
+	// defaultBatchWorkers is the number of chunks requested concurrently
+	defaultBatchWorkers = 4
+)
+
+// UsersByUUIDs looks up many users at once. Duplicates are dropped, large
+// inputs are split into chunks requested by a pool of WithBatchWorkers
+// workers. The timeout applies to each chunk, not to the whole lookup.
+func (api *UsersAPI) UsersByUUIDs(userUUIDs []uuid.UUID) (*models.UsersBatch, error) {
+	chunks := chunkUUIDs(dedupUUIDs(userUUIDs), batchChunkSize)
+	results := make([]*models.UsersBatch, len(chunks))
+
+	// ctx is cancelled by the first failed chunk, the others stop with it
+	ctx, cancel := context.WithCancel(api.context())
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for range min(api.batchWorkers, len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				resp, err := api.usersByUUIDsChunk(ctx, chunks[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[i] = resp
+			}
+		}()
+	}
+
+send:
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("usersByUUIDs api request: %w", firstErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("usersByUUIDs api request: %w", err)
+	}
+
+	batch := &models.UsersBatch{}
+	for _, r := range results {
+		batch.Users = append(batch.Users, r.Users...)
+		batch.Missing = append(batch.Missing, r.Missing...)
+	}
+	return batch, nil
+}
+
+func (api *UsersAPI) usersByUUIDsChunk(ctx context.Context, chunk []uuid.UUID) (*models.UsersBatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.UsersByUUIDs(ctx, models.UsersByUUIDsRequest(chunk))
+	if err != nil {
+		return nil, err
+	}
+	return models.UsersBatchFromProto(resp), nil
+}
+
+func dedupUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+func chunkUUIDs(ids []uuid.UUID, size int) [][]uuid.UUID {
+	chunks := make([][]uuid.UUID, 0, (len(ids)+size-1)/size)
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUsersByUUIDsWorkerPool(t *testing.T) {
+	var inFlight, peak atomic.Int64
+	srv := &testServer{}
+	srv.usersByUUIDs = func(_ context.Context, req *proto.UsersByUUIDsRequest) (*proto.UsersByUUIDsResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		resp := &proto.UsersByUUIDsResponse{}
+		for _, id := range req.UserUuids {
+			resp.Users = append(resp.Users, &proto.User{UserUuid: id})
+		}
+		return resp, nil
+	}
+	addr, _ := startTestServer(t, srv)
+
+	api, err := NewClient(addr, WithBatchWorkers(2), WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	ids := newUUIDs(10*batchChunkSize + 1)
+	batch, err := api.UsersByUUIDs(ids)
+	if err != nil {
+		t.Fatalf("UsersByUUIDs: %v", err)
+	}
+	if len(batch.Users) != len(ids) {
+		t.Fatalf("got %d users, want %d", len(batch.Users), len(ids))
+	}
+	if n := srv.calls.Load(); n != 11 {
+		t.Fatalf("got %d requests, want 11 chunks", n)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("%d chunks in flight, want at most 2 workers", p)
+	}
+}
+
+func TestUsersByUUIDsTimeoutPerChunk(t *testing.T) {
+	srv := &testServer{}
+	srv.usersByUUIDs = func(ctx context.Context, _ *proto.UsersByUUIDsRequest) (*proto.UsersByUUIDsResponse, error) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return &proto.UsersByUUIDsResponse{}, nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	addr, _ := startTestServer(t, srv)
+
+	// 4 sequential chunks take longer than the timeout, each one alone does not
+	api, err := NewClient(addr, WithBatchWorkers(1), WithTimeout(300*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	if _, err := api.UsersByUUIDs(newUUIDs(4 * batchChunkSize)); err != nil {
+		t.Fatalf("UsersByUUIDs: %v", err)
+	}
+}
+
+func TestUsersByUUIDsStopsOnFirstError(t *testing.T) {
+	srv := &testServer{}
+	srv.usersByUUIDs = func(context.Context, *proto.UsersByUUIDsRequest) (*proto.UsersByUUIDsResponse, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	}
+	addr, _ := startTestServer(t, srv)
+
+	api, err := NewClient(addr, WithBatchWorkers(1), WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	_, err = api.UsersByUUIDs(newUUIDs(5 * batchChunkSize))
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, want the chunk error", err)
+	}
+	if n := srv.calls.Load(); n > 2 {
+		t.Fatalf("got %d requests after the first failure", n)
+	}
+}
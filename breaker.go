@@ -0,0 +1,327 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned without calling the server while the circuit of the method is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker of one method
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// windowBuckets is the resolution of the failure rate window
+const windowBuckets = 10
+
+// BreakerConfig configures the per method circuit breakers, zero fields get defaults
+type BreakerConfig struct {
+	// Window is the period the failure rate is measured over, 10s by default
+	Window time.Duration
+	// MinRequests in the window before the circuit may open, 20 by default
+	MinRequests int
+	// FailureRatio opens the circuit, 0.5 by default
+	FailureRatio float64
+	// Cooldown is how long the circuit stays open before probing, 30s by default
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of probes that must succeed to close the circuit, 1 by default
+	HalfOpenRequests int
+	// OnStateChange is called outside of the breaker lock for every transition
+	OnStateChange func(method string, from, to CircuitState)
+	// Now is the clock, time.Now by default
+	Now func() time.Time
+}
+
+func (c *BreakerConfig) setDefaults() {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.FailureRatio <= 0 {
+		c.FailureRatio = 0.5
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// WithCircuitBreaker makes every method fail fast with ErrCircuitOpen
+// after too many of its calls failed with server side errors
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(api *UsersAPI) {
+		cfg.setDefaults()
+		api.breakers = &breakers{cfg: cfg, byMethod: make(map[string]*breaker)}
+	}
+}
+
+// CircuitStates returns the circuit state per full method name,
+// empty without WithCircuitBreaker
+func (api *UsersAPI) CircuitStates() map[string]CircuitState {
+	if api.breakers == nil {
+		return map[string]CircuitState{}
+	}
+	return api.breakers.states()
+}
+
+type breakers struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	byMethod map[string]*breaker
+}
+
+func (b *breakers) get(method string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.byMethod[method]
+	if !ok {
+		br = &breaker{method: method, cfg: &b.cfg}
+		b.byMethod[method] = br
+	}
+	return br
+}
+
+func (b *breakers) states() map[string]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make(map[string]CircuitState, len(b.byMethod))
+	for method, br := range b.byMethod {
+		out[method] = br.currentState()
+	}
+	return out
+}
+
+func (b *breakers) unaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if skipsClientLimits(ctx) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	br := b.get(method)
+	generation, err := br.allow()
+	if err != nil {
+		return err
+	}
+	err = invoker(ctx, method, req, reply, cc, opts...)
+	br.done(generation, err)
+	return err
+}
+
+func (b *breakers) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if skipsClientLimits(ctx) {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	br := b.get(method)
+	generation, err := br.allow()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	br.done(generation, err)
+	return stream, err
+}
+
+// isServerFailure tells errors of an unhealthy service from errors caused by the request.
+// Errors without a gRPC status were never answered by the server.
+func isServerFailure(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown,
+		codes.ResourceExhausted, codes.Aborted, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+type bucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// breaker is the circuit of one method
+type breaker struct {
+	method string
+	cfg    *BreakerConfig
+
+	mu    sync.Mutex
+	state CircuitState
+	// generation grows with every state change, calls are only
+	// counted in the state they were allowed in
+	generation uint64
+	openedAt   time.Time
+	probes     int // half-open calls in flight
+	passed     int // half-open calls succeeded
+	buckets    [windowBuckets]bucket
+}
+
+func (br *breaker) currentState() CircuitState {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	return br.state
+}
+
+// allow returns the generation the call is admitted under, or ErrCircuitOpen
+func (br *breaker) allow() (uint64, error) {
+	br.mu.Lock()
+	from := br.state
+	now := br.cfg.Now()
+	if br.state == CircuitOpen && now.Sub(br.openedAt) >= br.cfg.Cooldown {
+		br.setState(CircuitHalfOpen)
+	}
+
+	var err error
+	switch br.state {
+	case CircuitOpen:
+		retry := br.cfg.Cooldown - now.Sub(br.openedAt)
+		err = fmt.Errorf("%s: %w, retry in %s", br.method, ErrCircuitOpen, retry.Round(time.Millisecond))
+	case CircuitHalfOpen:
+		if br.probes+br.passed >= br.cfg.HalfOpenRequests {
+			err = fmt.Errorf("%s: %w, probing", br.method, ErrCircuitOpen)
+		} else {
+			br.probes++
+		}
+	}
+	to, generation := br.state, br.generation
+	br.mu.Unlock()
+
+	br.notify(from, to)
+	return generation, err
+}
+
+// record counts the outcome of a call allowed under generation,
+// calls allowed before the last state change are ignored
+func (br *breaker) record(generation uint64, failed bool) {
+	br.mu.Lock()
+	if generation != br.generation {
+		br.mu.Unlock()
+		return
+	}
+	from := br.state
+	now := br.cfg.Now()
+
+	switch br.state {
+	case CircuitHalfOpen:
+		if br.probes > 0 {
+			br.probes--
+		}
+		if failed {
+			br.trip(now)
+		} else if br.passed++; br.passed >= br.cfg.HalfOpenRequests {
+			br.buckets = [windowBuckets]bucket{}
+			br.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		b := br.bucket(now)
+		b.total++
+		if failed {
+			b.failures++
+		}
+		total, failures := br.window(now)
+		if total >= br.cfg.MinRequests && float64(failures)/float64(total) >= br.cfg.FailureRatio {
+			br.trip(now)
+		}
+	}
+	to := br.state
+	br.mu.Unlock()
+
+	br.notify(from, to)
+}
+
+// done records the outcome of a call allowed under generation. A call stopped
+// by a client limit never reached the server, it is not counted and only
+// gives back its half-open probe.
+func (br *breaker) done(generation uint64, err error) {
+	var limited *limitError
+	if errors.As(err, &limited) {
+		br.release(generation)
+		return
+	}
+	br.record(generation, isServerFailure(err))
+}
+
+// release gives back the probe of a half-open call allowed under generation
+func (br *breaker) release(generation uint64) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if generation == br.generation && br.state == CircuitHalfOpen && br.probes > 0 {
+		br.probes--
+	}
+}
+
+func (br *breaker) trip(now time.Time) {
+	br.openedAt = now
+	br.setState(CircuitOpen)
+}
+
+func (br *breaker) setState(s CircuitState) {
+	br.state = s
+	br.generation++
+	br.probes = 0
+	br.passed = 0
+}
+
+func (br *breaker) notify(from, to CircuitState) {
+	if from != to && br.cfg.OnStateChange != nil {
+		br.cfg.OnStateChange(br.method, from, to)
+	}
+}
+
+// bucket returns the bucket for now, resetting it if it belongs to an older period
+func (br *breaker) bucket(now time.Time) *bucket {
+	width := br.cfg.Window / windowBuckets
+	start := now.Truncate(width)
+	b := &br.buckets[(start.UnixNano()/int64(width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+func (br *breaker) window(now time.Time) (total, failures int) {
+	for _, b := range br.buckets {
+		if now.Sub(b.start) < br.cfg.Window {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
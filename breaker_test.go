@@ -0,0 +1,287 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock is a BreakerConfig.Now moved forward by the test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type transition struct {
+	from, to CircuitState
+}
+
+func newTestBreaker(cfg BreakerConfig) (*breaker, *fakeClock, *[]transition) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var transitions []transition
+	cfg.Now = clock.Now
+	cfg.OnStateChange = func(_ string, from, to CircuitState) {
+		transitions = append(transitions, transition{from, to})
+	}
+	cfg.setDefaults()
+	return &breaker{method: "/test/Method", cfg: &cfg}, clock, &transitions
+}
+
+// call runs one call through br, it returns false when the circuit rejected it
+func call(t *testing.T, br *breaker, failed bool) bool {
+	t.Helper()
+	generation, err := br.allow()
+	if err != nil {
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("allow: %v", err)
+		}
+		return false
+	}
+	br.record(generation, failed)
+	return true
+}
+
+func TestBreakerOpensOnFailureRatio(t *testing.T) {
+	br, _, _ := newTestBreaker(BreakerConfig{MinRequests: 4, FailureRatio: 0.5})
+
+	call(t, br, false)
+	call(t, br, true)
+	call(t, br, false)
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s below MinRequests", br.currentState())
+	}
+	call(t, br, true)
+	if br.currentState() != CircuitOpen {
+		t.Fatalf("state = %s after 2 of 4 failed, want open", br.currentState())
+	}
+	if call(t, br, false) {
+		t.Fatal("open circuit allowed a call")
+	}
+}
+
+func TestBreakerForgetsOldFailures(t *testing.T) {
+	br, clock, _ := newTestBreaker(BreakerConfig{Window: 10 * time.Second, MinRequests: 4})
+
+	call(t, br, true)
+	call(t, br, true)
+	call(t, br, true)
+	clock.Advance(11 * time.Second)
+	call(t, br, true)
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s, failures outside the window were counted", br.currentState())
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	br, clock, transitions := newTestBreaker(BreakerConfig{MinRequests: 2, Cooldown: 30 * time.Second})
+
+	call(t, br, true)
+	call(t, br, true)
+	clock.Advance(29 * time.Second)
+	if call(t, br, false) {
+		t.Fatal("call allowed before the cooldown passed")
+	}
+
+	// a failed probe opens the circuit again
+	clock.Advance(time.Second)
+	if !call(t, br, true) {
+		t.Fatal("probe rejected after the cooldown")
+	}
+	if br.currentState() != CircuitOpen {
+		t.Fatalf("state = %s after a failed probe, want open", br.currentState())
+	}
+
+	clock.Advance(30 * time.Second)
+	if !call(t, br, false) {
+		t.Fatal("probe rejected after the cooldown")
+	}
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s after a passed probe, want closed", br.currentState())
+	}
+
+	want := []transition{
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitClosed},
+	}
+	if len(*transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", *transitions, want)
+	}
+	for i := range want {
+		if (*transitions)[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", *transitions, want)
+		}
+	}
+}
+
+func TestBreakerHalfOpenLimitsProbes(t *testing.T) {
+	br, clock, _ := newTestBreaker(BreakerConfig{MinRequests: 1, HalfOpenRequests: 2})
+
+	call(t, br, true)
+	clock.Advance(30 * time.Second)
+
+	first, err := br.allow()
+	if err != nil {
+		t.Fatalf("first probe: %v", err)
+	}
+	second, err := br.allow()
+	if err != nil {
+		t.Fatalf("second probe: %v", err)
+	}
+	if _, err := br.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third probe error = %v, want ErrCircuitOpen", err)
+	}
+
+	br.record(first, false)
+	if br.currentState() != CircuitHalfOpen {
+		t.Fatalf("state = %s after 1 of 2 probes, want half-open", br.currentState())
+	}
+	br.record(second, false)
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s after 2 of 2 probes, want closed", br.currentState())
+	}
+}
+
+// TestBreakerIgnoresCallsOfOldState checks a slow call allowed while closed
+// is not taken for a probe after the circuit opened and went half-open
+func TestBreakerIgnoresCallsOfOldState(t *testing.T) {
+	br, clock, _ := newTestBreaker(BreakerConfig{MinRequests: 2})
+
+	slow, err := br.allow()
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	call(t, br, true)
+	call(t, br, true)
+	clock.Advance(30 * time.Second)
+
+	probe, err := br.allow()
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	br.record(slow, false)
+	if br.currentState() != CircuitHalfOpen {
+		t.Fatalf("state = %s, the slow call closed the circuit", br.currentState())
+	}
+	if _, err := br.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe error = %v, want ErrCircuitOpen", err)
+	}
+
+	br.record(probe, false)
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s after the probe passed, want closed", br.currentState())
+	}
+}
+
+func throttled() error {
+	return &limitError{method: "/test/Method", limit: "rate", err: context.DeadlineExceeded}
+}
+
+// TestBreakerThrottledProbe checks a probe stopped by a client limit
+// neither closes the circuit nor keeps its probe slot
+func TestBreakerThrottledProbe(t *testing.T) {
+	br, clock, _ := newTestBreaker(BreakerConfig{MinRequests: 1})
+
+	call(t, br, true)
+	clock.Advance(30 * time.Second)
+
+	probe, err := br.allow()
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	br.done(probe, throttled())
+	if br.currentState() != CircuitHalfOpen {
+		t.Fatalf("state = %s after a throttled probe, want half-open", br.currentState())
+	}
+
+	probe, err = br.allow()
+	if err != nil {
+		t.Fatalf("probe after a throttled one: %v", err)
+	}
+	br.done(probe, nil)
+	if br.currentState() != CircuitClosed {
+		t.Fatalf("state = %s after the probe passed, want closed", br.currentState())
+	}
+}
+
+func TestBreakerIgnoresThrottledCalls(t *testing.T) {
+	br, _, _ := newTestBreaker(BreakerConfig{MinRequests: 4, FailureRatio: 0.5})
+
+	for range 10 {
+		generation, err := br.allow()
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		br.done(generation, throttled())
+	}
+	call(t, br, true)
+	call(t, br, false)
+	call(t, br, true)
+	call(t, br, false)
+	if br.currentState() != CircuitOpen {
+		t.Fatalf("state = %s, throttled calls diluted the failure ratio", br.currentState())
+	}
+}
+
+// TestBreakerWithLimiter runs a half-open probe through the interceptor
+// chain of NewClient, where the limiter runs inside the breaker
+func TestBreakerWithLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := &breakers{cfg: BreakerConfig{MinRequests: 1, Now: clock.Now}, byMethod: make(map[string]*breaker)}
+	b.cfg.setDefaults()
+	l, err := newLimiters(map[string]LimitConfig{"": {RequestsPerSecond: 0.001}}, nil)
+	if err != nil {
+		t.Fatalf("newLimiters: %v", err)
+	}
+
+	const method = "/service.UserService/UserBy"
+	served := 0
+	server := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		served++
+		return status.Error(codes.Unavailable, "down")
+	}
+	invoke := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		return b.unaryInterceptor(ctx, method, nil, nil, nil,
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return l.unaryInterceptor(ctx, method, req, reply, cc, server, opts...)
+			})
+	}
+
+	// the only token of the bucket is taken by the call opening the circuit
+	if err := invoke(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("first call: %v", err)
+	}
+	clock.Advance(30 * time.Second)
+
+	if err := invoke(); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("probe error = %v, want the limiter deadline", err)
+	}
+	if served != 1 {
+		t.Fatalf("server got %d calls, the probe was throttled", served)
+	}
+	if state := b.states()[method]; state != CircuitHalfOpen {
+		t.Fatalf("state = %s after a throttled probe, want half-open", state)
+	}
+}
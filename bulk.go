@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+)
+
+// ImportUsers has no deadline of its own, a large import may take long.
+// Use WithContext to bound it. A record error yielded as *models.ImportError
+// is reported in the result, any other error stops the import.
+func (api *UsersAPI) ImportUsers(records iter.Seq2[*models.ImportRecord, error], dryRun bool) (*models.ImportResult, error) {
+	ctx, cancel := context.WithCancel(api.context())
+	defer cancel()
+
+	stream, err := api.UserServiceClient.ImportUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("importUsers api request: %w", err)
+	}
+	options := &proto.ImportUsersRequest{
+		Item: &proto.ImportUsersRequest_Options{Options: &proto.ImportOptions{DryRun: dryRun}},
+	}
+	if err := stream.Send(options); err != nil {
+		return nil, fmt.Errorf("importUsers api request: %w", sendErr(stream, err))
+	}
+
+	// records rejected before sending, capped like the server errors
+	rejected := &models.ImportResult{}
+	index := 0
+	for rec, err := range records {
+		i := index
+		index++
+
+		if err != nil {
+			var ie *models.ImportError
+			if !errors.As(err, &ie) {
+				return nil, fmt.Errorf("importUsers: record %d: %w", i, err)
+			}
+			ie.Index = i
+			rejected.AddError(ie)
+			continue
+		}
+		if err := rec.Validate(); err != nil {
+			rejected.AddError(&models.ImportError{Index: i, Email: rec.User.Email, Message: err.Error()})
+			continue
+		}
+
+		req := &proto.ImportUsersRequest{
+			Item: &proto.ImportUsersRequest_Record{Record: rec.Proto(i)},
+		}
+		if err := stream.Send(req); err != nil {
+			return nil, fmt.Errorf("importUsers api request: %w", sendErr(stream, err))
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("importUsers api request: %w", err)
+	}
+	result := models.ImportResultFromProto(resp)
+	result.Merge(rejected)
+	return result, nil
+}
+
+// sendErr returns the status the server ended the stream with,
+// Send itself only reports io.EOF then
+func sendErr(stream proto.UserService_ImportUsersClient, err error) error {
+	if errors.Is(err, io.EOF) {
+		if _, rerr := stream.CloseAndRecv(); rerr != nil {
+			return rerr
+		}
+	}
+	return err
+}
+
+// ExportUsers has no deadline of its own, the stream is cancelled when the
+// iteration stops. A failed stream is yielded as the error and stops the iteration.
+func (api *UsersAPI) ExportUsers(filter models.ListUsersFilter) iter.Seq2[*models.User, error] {
+	return func(yield func(*models.User, error) bool) {
+		ctx, cancel := context.WithCancel(api.context())
+		defer cancel()
+
+		stream, err := api.UserServiceClient.ExportUsers(ctx, filter.Proto(""))
+		if err != nil {
+			yield(nil, fmt.Errorf("exportUsers api request: %w", err))
+			return
+		}
+		for {
+			pb, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("exportUsers api request: %w", err))
+				return
+			}
+			if !yield(models.UserFromProto(pb), nil) {
+				return
+			}
+		}
+	}
+}
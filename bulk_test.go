@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+)
+
+// importClient is a UserServiceClient whose ImportUsers stream records
+// what is sent and answers like a server creating every record
+type importClient struct {
+	proto.UserServiceClient
+	stream *importStream
+}
+
+func (c *importClient) ImportUsers(context.Context, ...grpc.CallOption) (grpc.ClientStreamingClient[proto.ImportUsersRequest, proto.ImportUsersResponse], error) {
+	return c.stream, nil
+}
+
+type importStream struct {
+	grpc.ClientStream
+	sent   []*proto.ImportUsersRequest
+	closed bool
+}
+
+func (s *importStream) Send(req *proto.ImportUsersRequest) error {
+	if s.closed {
+		return io.EOF
+	}
+	s.sent = append(s.sent, req)
+	return nil
+}
+
+func (s *importStream) CloseAndRecv() (*proto.ImportUsersResponse, error) {
+	s.closed = true
+	resp := &proto.ImportUsersResponse{}
+	for _, req := range s.sent {
+		switch item := req.Item.(type) {
+		case *proto.ImportUsersRequest_Options:
+			resp.DryRun = item.Options.DryRun
+		case *proto.ImportUsersRequest_Record:
+			resp.Created++
+		}
+	}
+	return resp, nil
+}
+
+func importRecord(email string) *models.ImportRecord {
+	return &models.ImportRecord{User: models.User{UserUUID: uuid.Must(uuid.NewV4()), Email: email}}
+}
+
+func TestImportUsersRejectsBeforeSending(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		stream := &importStream{}
+		api := &UsersAPI{UserServiceClient: &importClient{stream: stream}, timeout: time.Second}
+
+		records := func(yield func(*models.ImportRecord, error) bool) {
+			_ = yield(importRecord("first@example.com"), nil) &&
+				yield(nil, &models.ImportError{Email: "malformed@example.com", Message: "line 3: user_type"}) &&
+				yield(importRecord("not an email"), nil) &&
+				yield(importRecord("last@example.com"), nil)
+		}
+		result, err := api.ImportUsers(records, dryRun)
+		if err != nil {
+			t.Fatalf("ImportUsers: %v", err)
+		}
+
+		options := stream.sent[0].GetOptions()
+		if options == nil || options.DryRun != dryRun {
+			t.Fatalf("first message = %v, want the options with dry run %t", stream.sent[0], dryRun)
+		}
+		var sent []int64
+		for _, req := range stream.sent[1:] {
+			sent = append(sent, req.GetRecord().Index)
+		}
+		if len(sent) != 2 || sent[0] != 0 || sent[1] != 3 {
+			t.Fatalf("sent record indexes %v, want [0 3]", sent)
+		}
+
+		if result.Created != 2 || result.Failed != 2 || result.DryRun != dryRun {
+			t.Fatalf("result = %+v", result)
+		}
+		if len(result.Errors) != 2 {
+			t.Fatalf("errors = %+v", result.Errors)
+		}
+		if e := result.Errors[0]; e.Index != 1 || e.Email != "malformed@example.com" {
+			t.Fatalf("first error = %+v, want the malformed record at index 1", e)
+		}
+		if e := result.Errors[1]; e.Index != 2 || e.Email != "not an email" || !strings.Contains(e.Message, "email") {
+			t.Fatalf("second error = %+v, want the invalid email at index 2", e)
+		}
+	}
+}
+
+func TestImportUsersStopsOnReadError(t *testing.T) {
+	stream := &importStream{}
+	api := &UsersAPI{UserServiceClient: &importClient{stream: stream}, timeout: time.Second}
+
+	broken := errors.New("disk gone")
+	records := func(yield func(*models.ImportRecord, error) bool) {
+		_ = yield(importRecord("first@example.com"), nil) && yield(nil, broken)
+	}
+	if _, err := api.ImportUsers(records, false); !errors.Is(err, broken) || !strings.Contains(err.Error(), "record 1") {
+		t.Fatalf("ImportUsers error = %v, want the read error of record 1", err)
+	}
+	if stream.closed {
+		t.Fatal("the import was committed after a read error")
+	}
+}
+
+// TestImportUsersFromCSV runs ReadCSV into ImportUsers, the indexes of
+// rejected records count the malformed rows as well
+func TestImportUsersFromCSV(t *testing.T) {
+	stream := &importStream{}
+	api := &UsersAPI{UserServiceClient: &importClient{stream: stream}, timeout: time.Second}
+
+	input := "email,user_type\na@example.com,1\nb@example.com,x\nc@example.com,2\n"
+	result, err := api.ImportUsers(ReadCSV(strings.NewReader(input)), false)
+	if err != nil {
+		t.Fatalf("ImportUsers: %v", err)
+	}
+	if result.Created != 2 || result.Failed != 1 || len(result.Errors) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if e := result.Errors[0]; e.Index != 1 || e.Email != "b@example.com" || !strings.HasPrefix(e.Message, "line 3:") {
+		t.Fatalf("error = %+v, want index 1 from line 3", e)
+	}
+	if last := stream.sent[len(stream.sent)-1].GetRecord(); last.Index != 2 || last.User.Email != "c@example.com" {
+		t.Fatalf("last record sent = %v, want c@example.com at index 2", last)
+	}
+}
@@ -0,0 +1,277 @@
+package user
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+// maxJSONLLine is the longest JSONL line ReadJSONL accepts
+const maxJSONLLine = 1 << 20
+
+// bulkColumns are the CSV header written by WriteCSV, ReadCSV needs only email.
+// JSONL objects use the same names.
+var bulkColumns = []string{
+	"uuid", "email", "username", "first_name", "last_name", "avatar",
+	"user_type", "status", "created_at", "password_hash", "password_hash_format",
+}
+
+// bulkRecord is one user of the CSV and JSONL formats
+type bulkRecord struct {
+	UUID               string `json:"uuid,omitempty"`
+	Email              string `json:"email"`
+	Username           string `json:"username,omitempty"`
+	FirstName          string `json:"first_name,omitempty"`
+	LastName           string `json:"last_name,omitempty"`
+	Avatar             string `json:"avatar,omitempty"`
+	UserType           int    `json:"user_type"`
+	Status             string `json:"status,omitempty"`
+	CreatedAt          string `json:"created_at,omitempty"`
+	PasswordHash       string `json:"password_hash,omitempty"`
+	PasswordHashFormat string `json:"password_hash_format,omitempty"`
+}
+
+func bulkRecordFromUser(u *models.User) bulkRecord {
+	b := bulkRecord{
+		UUID:      u.UserUUID.String(),
+		Email:     u.Email,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Avatar:    u.Avatar,
+		UserType:  u.UserType,
+		Status:    u.Status.String(),
+	}
+	if !u.CreatedAt.IsZero() {
+		b.CreatedAt = u.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return b
+}
+
+func (b bulkRecord) values() []string {
+	return []string{
+		b.UUID, b.Email, b.Username, b.FirstName, b.LastName, b.Avatar,
+		strconv.Itoa(b.UserType), b.Status, b.CreatedAt, b.PasswordHash, b.PasswordHashFormat,
+	}
+}
+
+// importRecord converts the record, a missing uuid is generated and a
+// missing hash format is detected from the hash
+func (b bulkRecord) importRecord() (*models.ImportRecord, error) {
+	rec := &models.ImportRecord{
+		User: models.User{
+			Email:     b.Email,
+			Username:  b.Username,
+			FirstName: b.FirstName,
+			LastName:  b.LastName,
+			Avatar:    b.Avatar,
+			UserType:  b.UserType,
+		},
+		PasswordHash: b.PasswordHash,
+	}
+
+	var err error
+	if b.UUID == "" {
+		rec.User.UserUUID, err = uuid.NewV4()
+	} else {
+		rec.User.UserUUID, err = uuid.FromString(b.UUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("uuid: %w", err)
+	}
+	if b.Status != "" {
+		if rec.User.Status, err = models.ParseUserStatus(b.Status); err != nil {
+			return nil, err
+		}
+	}
+	if b.CreatedAt != "" {
+		if rec.User.CreatedAt, err = time.Parse(time.RFC3339Nano, b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("created_at: %w", err)
+		}
+	}
+	switch {
+	case b.PasswordHashFormat != "":
+		if rec.HashFormat, err = models.ParsePasswordHashFormat(b.PasswordHashFormat); err != nil {
+			return nil, err
+		}
+	case b.PasswordHash != "":
+		rec.HashFormat = models.DetectPasswordHashFormat(b.PasswordHash)
+	}
+	return rec, nil
+}
+
+// ReadCSV reads import records from CSV with a header row naming the
+// columns written by WriteCSV, unknown columns and a byte order mark are
+// ignored. Malformed rows are yielded as *models.ImportError, so ImportUsers
+// reports and skips them.
+func ReadCSV(r io.Reader) iter.Seq2[*models.ImportRecord, error] {
+	return func(yield func(*models.ImportRecord, error) bool) {
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+		header, err := cr.Read()
+		if err != nil {
+			yield(nil, fmt.Errorf("read csv header: %w", err))
+			return
+		}
+		// spreadsheet exports often start with a UTF-8 byte order mark
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[name] = i
+		}
+		if _, ok := columns["email"]; !ok {
+			yield(nil, errors.New("read csv header: no email column"))
+			return
+		}
+
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				if !yield(nil, lineError(pe.Line, pe.Err, "")) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("read csv: %w", err))
+				return
+			}
+			line, _ := cr.FieldPos(0)
+
+			field := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(row) {
+					return row[i]
+				}
+				return ""
+			}
+			b := bulkRecord{
+				UUID:               field("uuid"),
+				Email:              field("email"),
+				Username:           field("username"),
+				FirstName:          field("first_name"),
+				LastName:           field("last_name"),
+				Avatar:             field("avatar"),
+				Status:             field("status"),
+				CreatedAt:          field("created_at"),
+				PasswordHash:       field("password_hash"),
+				PasswordHashFormat: field("password_hash_format"),
+			}
+			if t := field("user_type"); t != "" {
+				if b.UserType, err = strconv.Atoi(t); err != nil {
+					if !yield(nil, lineError(line, fmt.Errorf("user_type: %w", err), b.Email)) {
+						return
+					}
+					continue
+				}
+			}
+
+			rec, err := b.importRecord()
+			if err != nil {
+				rec, err = nil, lineError(line, err, b.Email)
+			}
+			if !yield(rec, err) {
+				return
+			}
+		}
+	}
+}
+
+// ReadJSONL reads import records from one JSON object per line, blank lines
+// are skipped. Malformed lines are yielded as *models.ImportError.
+func ReadJSONL(r io.Reader) iter.Seq2[*models.ImportRecord, error] {
+	return func(yield func(*models.ImportRecord, error) bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), maxJSONLLine)
+		line := 0
+		for sc.Scan() {
+			line++
+			raw := sc.Bytes()
+			if len(raw) == 0 {
+				continue
+			}
+
+			var b bulkRecord
+			if err := json.Unmarshal(raw, &b); err != nil {
+				if !yield(nil, lineError(line, err, "")) {
+					return
+				}
+				continue
+			}
+			rec, err := b.importRecord()
+			if err != nil {
+				rec, err = nil, lineError(line, err, b.Email)
+			}
+			if !yield(rec, err) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, fmt.Errorf("read jsonl line %d: %w", line+1, err))
+		}
+	}
+}
+
+func lineError(line int, err error, email string) *models.ImportError {
+	return &models.ImportError{Email: email, Message: fmt.Sprintf("line %d: %s", line, err)}
+}
+
+// WriteCSV writes the users with a header row in the format read by ReadCSV
+// and returns how many were written. Passwords are never exported.
+func WriteCSV(w io.Writer, users iter.Seq2[*models.User, error]) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(bulkColumns); err != nil {
+		return 0, fmt.Errorf("write csv: %w", err)
+	}
+	n := 0
+	for u, err := range users {
+		if err != nil {
+			cw.Flush()
+			return n, err
+		}
+		if err := cw.Write(bulkRecordFromUser(u).values()); err != nil {
+			return n, fmt.Errorf("write csv: %w", err)
+		}
+		n++
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return n, fmt.Errorf("write csv: %w", err)
+	}
+	return n, nil
+}
+
+// WriteJSONL writes one JSON object per user in the format read by ReadJSONL
+// and returns how many were written. Passwords are never exported.
+func WriteJSONL(w io.Writer, users iter.Seq2[*models.User, error]) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	for u, err := range users {
+		if err != nil {
+			bw.Flush()
+			return n, err
+		}
+		if err := enc.Encode(bulkRecordFromUser(u)); err != nil {
+			return n, fmt.Errorf("write jsonl: %w", err)
+		}
+		n++
+	}
+	if err := bw.Flush(); err != nil {
+		return n, fmt.Errorf("write jsonl: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,259 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+// bcryptHash is a well formed bcrypt hash
+var bcryptHash = "$2a$10$" + strings.Repeat("a", 53)
+
+func exportedUsers() []*models.User {
+	return []*models.User{
+		{
+			UserUUID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Username: "jane",
+			FirstName: "Jane", LastName: "Doe, Jr.", Avatar: "https://example.com/jane.png",
+			UserType: 2, Status: models.StatusDeactivated,
+			CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 123, time.UTC),
+		},
+		{UserUUID: uuid.Must(uuid.NewV4()), Email: "joe@example.com", FirstName: "Jo\"e\nline"},
+	}
+}
+
+func seqOf[T any](items ...T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readAll collects the records and errors of seq
+func readAll(seq iter.Seq2[*models.ImportRecord, error]) ([]*models.ImportRecord, []error) {
+	var (
+		records []*models.ImportRecord
+		errs    []error
+	)
+	for rec, err := range seq {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, errs
+}
+
+func TestBulkRoundTrip(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(*bytes.Buffer, iter.Seq2[*models.User, error]) (int, error)
+		read  func(*bytes.Buffer) iter.Seq2[*models.ImportRecord, error]
+	}{
+		{
+			"csv",
+			func(b *bytes.Buffer, users iter.Seq2[*models.User, error]) (int, error) { return WriteCSV(b, users) },
+			func(b *bytes.Buffer) iter.Seq2[*models.ImportRecord, error] { return ReadCSV(b) },
+		},
+		{
+			"jsonl",
+			func(b *bytes.Buffer, users iter.Seq2[*models.User, error]) (int, error) { return WriteJSONL(b, users) },
+			func(b *bytes.Buffer) iter.Seq2[*models.ImportRecord, error] { return ReadJSONL(b) },
+		},
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			users := exportedUsers()
+			var buf bytes.Buffer
+			n, err := f.write(&buf, seqOf(users...))
+			if err != nil || n != len(users) {
+				t.Fatalf("write = %d, %v", n, err)
+			}
+
+			records, errs := readAll(f.read(&buf))
+			if len(errs) != 0 {
+				t.Fatalf("read errors: %v", errs)
+			}
+			if len(records) != len(users) {
+				t.Fatalf("read %d records, want %d", len(records), len(users))
+			}
+			for i, rec := range records {
+				want := *users[i]
+				if !rec.User.CreatedAt.Equal(want.CreatedAt) {
+					t.Fatalf("record %d created at %s, want %s", i, rec.User.CreatedAt, want.CreatedAt)
+				}
+				rec.User.CreatedAt = want.CreatedAt
+				if rec.User != want {
+					t.Fatalf("record %d = %+v, want %+v", i, rec.User, want)
+				}
+				if rec.PasswordHash != "" || rec.HashFormat != models.HashUnknown {
+					t.Fatalf("record %d has a password: %+v", i, rec)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteStopsOnError(t *testing.T) {
+	failed := errors.New("stream broken")
+	users := func(yield func(*models.User, error) bool) {
+		if yield(exportedUsers()[0], nil) {
+			yield(nil, failed)
+		}
+	}
+	var buf bytes.Buffer
+	if n, err := WriteCSV(&buf, users); n != 1 || !errors.Is(err, failed) {
+		t.Fatalf("WriteCSV = %d, %v", n, err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("%d csv lines written before the error, want header and 1 user", lines)
+	}
+	buf.Reset()
+	if n, err := WriteJSONL(&buf, users); n != 1 || !errors.Is(err, failed) {
+		t.Fatalf("WriteJSONL = %d, %v", n, err)
+	}
+}
+
+func TestReadCSVHeader(t *testing.T) {
+	for name, tt := range map[string]struct {
+		input string
+		err   string
+	}{
+		"no email column": {"uuid,username\n,jane\n", "no email column"},
+		"empty input":     {"", "read csv header"},
+		"byte order mark": {"\ufeffemail,username\njane@example.com,jane\n", ""},
+		"only email":      {"email\njane@example.com\n", ""},
+		"unknown columns": {"team,email,extra\nred,jane@example.com,x\n", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			records, errs := readAll(ReadCSV(strings.NewReader(tt.input)))
+			if tt.err != "" {
+				if len(errs) != 1 || len(records) != 0 || !strings.Contains(errs[0].Error(), tt.err) {
+					t.Fatalf("ReadCSV = %v, %v, want error %q", records, errs, tt.err)
+				}
+				var ie *models.ImportError
+				if errors.As(errs[0], &ie) {
+					t.Fatalf("header error %v is an ImportError, it must stop the import", errs[0])
+				}
+				return
+			}
+			if len(errs) != 0 || len(records) != 1 || records[0].User.Email != "jane@example.com" {
+				t.Fatalf("ReadCSV = %v, %v", records, errs)
+			}
+			if records[0].User.UserUUID == uuid.Nil {
+				t.Fatal("no uuid generated for a record without one")
+			}
+		})
+	}
+}
+
+// importErrors checks errs are *models.ImportError and returns their messages
+func importErrors(t *testing.T, errs []error) []string {
+	t.Helper()
+	var msgs []string
+	for _, err := range errs {
+		var ie *models.ImportError
+		if !errors.As(err, &ie) {
+			t.Fatalf("error %v is not an *models.ImportError", err)
+		}
+		msgs = append(msgs, ie.Email+" "+ie.Message)
+	}
+	return msgs
+}
+
+func TestReadCSVMalformedRows(t *testing.T) {
+	input := strings.Join([]string{
+		"email,uuid,user_type,status",
+		"ok@example.com,,1,active",
+		"type@example.com,,admin,",
+		"uuid@example.com,not-a-uuid,,",
+		"status@example.com,,,gone",
+		"short@example.com",
+		`"quote@example.com,,,`,
+	}, "\n") + "\n"
+
+	records, errs := readAll(ReadCSV(strings.NewReader(input)))
+	if len(records) != 1 || records[0].User.Email != "ok@example.com" || records[0].User.UserType != 1 {
+		t.Fatalf("records = %+v, want only ok@example.com", records)
+	}
+	msgs := importErrors(t, errs)
+	want := []string{
+		"type@example.com line 3: user_type",
+		"uuid@example.com line 4: uuid",
+		"status@example.com line 5: invalid status",
+		" line 6: wrong number of fields",
+		" line 7: extraneous or missing \" in quoted-field",
+	}
+	if len(msgs) != len(want) {
+		t.Fatalf("errors = %q, want %d", msgs, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(msgs[i], want[i]) {
+			t.Errorf("error %d = %q, want prefix %q", i, msgs[i], want[i])
+		}
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := strings.Join([]string{
+		`{"email":"jane@example.com","password_hash":"` + bcryptHash + `"}`,
+		``,
+		`{"email":"joe@example.com","password_hash":"secret","password_hash_format":"argon2id"}`,
+		`{"email":`,
+		`{"email":"bad@example.com","password_hash_format":"md5"}`,
+		``,
+		`{"email":"sam@example.com","password_hash":"plain"}`,
+	}, "\n")
+
+	records, errs := readAll(ReadJSONL(strings.NewReader(input)))
+	msgs := importErrors(t, errs)
+	if len(msgs) != 2 || !strings.HasPrefix(msgs[0], " line 4: ") ||
+		!strings.HasPrefix(msgs[1], "bad@example.com line 5: unsupported password hash format") {
+		t.Fatalf("errors = %q, want lines 4 and 5, blank lines counted", msgs)
+	}
+	want := []struct {
+		email  string
+		format models.PasswordHashFormat
+	}{
+		// the format is detected from the hash when it is not given
+		{"jane@example.com", models.HashBcrypt},
+		// a given format is taken as is, Validate checks the hash
+		{"joe@example.com", models.HashArgon2id},
+		{"sam@example.com", models.HashUnknown},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("read %d records, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		if records[i].User.Email != w.email || records[i].HashFormat != w.format {
+			t.Errorf("record %d = %s %s, want %s %s", i, records[i].User.Email, records[i].HashFormat, w.email, w.format)
+		}
+	}
+}
+
+func TestReadJSONLLongLine(t *testing.T) {
+	input := `{"email":"jane@example.com"}` + "\n" +
+		`{"email":"` + strings.Repeat("a", maxJSONLLine) + `@example.com"}` + "\n" +
+		`{"email":"joe@example.com"}` + "\n"
+
+	records, errs := readAll(ReadJSONL(strings.NewReader(input)))
+	if len(records) != 1 || records[0].User.Email != "jane@example.com" {
+		t.Fatalf("records = %+v, want the line before the long one", records)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 2") {
+		t.Fatalf("errors = %v, want one for line 2", errs)
+	}
+	var ie *models.ImportError
+	if errors.As(errs[0], &ie) {
+		t.Fatalf("long line error %v is an ImportError, it must stop the import", errs[0])
+	}
+}
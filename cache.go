@@ -0,0 +1,383 @@
+package user
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultCacheSize        = 10000
+	defaultCacheTTL         = time.Minute
+	defaultCacheNegativeTTL = 10 * time.Second
+)
+
+// CachedAPI wraps Client with LRU caches for UserByUUID and CheckAuth.
+// CheckAuth results are keyed by the SHA-256 of the token, the raw token
+// is never kept. UserByUUID results are kept apart per caller token of
+// WithContext, so a user read with one token is not served to another.
+// Every call returns its own copy of the user. Changes made through the
+// same CachedAPI, imports included, invalidate the user.
+type CachedAPI struct {
+	Client
+
+	// ctx is the context set by WithContext, its token scopes the users cache
+	ctx         context.Context
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	users *lru[userKey, cachedUser]
+	auth  *lru[[sha256.Size]byte, cachedUser]
+	gens  *generations
+}
+
+// userKey is the uuid and the SHA-256 of the caller token it was read with
+type userKey struct {
+	scope    [sha256.Size]byte
+	userUUID uuid.UUID
+}
+
+type cachedUser struct {
+	user    *models.User // nil for a cached not-found
+	expires time.Time
+}
+
+// CacheOption configures CachedAPI
+type CacheOption func(c *CachedAPI)
+
+// WithCacheSize sets the max number of entries of each cache
+func WithCacheSize(size int) CacheOption {
+	return func(c *CachedAPI) {
+		c.users = newLRU[userKey, cachedUser](size)
+		c.auth = newLRU[[sha256.Size]byte, cachedUser](size)
+	}
+}
+
+// WithCacheTTL sets how long found users and valid tokens are cached
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *CachedAPI) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheNegativeTTL sets how long not found users are cached, zero disables negative caching
+func WithCacheNegativeTTL(ttl time.Duration) CacheOption {
+	return func(c *CachedAPI) {
+		c.negativeTTL = ttl
+	}
+}
+
+// NewCache wraps api with a cache
+func NewCache(api Client, opts ...CacheOption) *CachedAPI {
+	c := &CachedAPI{
+		Client:      api,
+		ttl:         defaultCacheTTL,
+		negativeTTL: defaultCacheNegativeTTL,
+		now:         time.Now,
+		users:       newLRU[userKey, cachedUser](defaultCacheSize),
+		auth:        newLRU[[sha256.Size]byte, cachedUser](defaultCacheSize),
+		gens:        &generations{reading: make(map[uuid.UUID]*keyGeneration)},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithContext returns a CachedAPI sharing the caches whose calls use ctx
+func (c *CachedAPI) WithContext(ctx context.Context) Client {
+	cc := *c
+	cc.ctx = ctx
+	cc.Client = c.Client.WithContext(ctx)
+	return &cc
+}
+
+func (c *CachedAPI) userKey(userUUID uuid.UUID) userKey {
+	key := userKey{userUUID: userUUID}
+	if c.ctx != nil {
+		if token := tokenFromContext(c.ctx); token != "" {
+			key.scope = sha256.Sum256([]byte(token))
+		}
+	}
+	return key
+}
+
+// UserByUUID is
+func (c *CachedAPI) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
+	key := c.userKey(userUUID)
+	if e, ok := c.users.get(key); ok && c.now().Before(e.expires) {
+		if e.user == nil {
+			return nil, fmt.Errorf("cached user %s: %w", userUUID, models.ErrUserNotFound)
+		}
+		return clone(e.user), nil
+	}
+
+	// a read that overlaps an Invalidate may have the user from before
+	// the change, it is returned but not cached
+	gen := c.gens.begin(userUUID)
+	user, err := c.Client.UserByUUID(userUUID)
+	c.gens.end(userUUID, gen, func() {
+		switch {
+		case err == nil:
+			c.users.add(key, cachedUser{user: clone(user), expires: c.now().Add(c.ttl)})
+		case c.negativeTTL > 0 && errors.Is(err, models.ErrUserNotFound):
+			c.users.add(key, cachedUser{expires: c.now().Add(c.negativeTTL)})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CheckAuth is
+func (c *CachedAPI) CheckAuth(token []byte) (*models.User, error) {
+	key := sha256.Sum256(token)
+	if e, ok := c.auth.get(key); ok && c.now().Before(e.expires) {
+		return clone(e.user), nil
+	}
+
+	// the user of the token is not known before the call, so any
+	// Invalidate during it keeps the result out of the cache
+	gen := c.gens.current()
+	user, err := c.Client.CheckAuth(token)
+	if err != nil {
+		return nil, err
+	}
+	c.gens.endAny(gen, func() {
+		c.auth.add(key, cachedUser{user: clone(user), expires: c.now().Add(c.ttl)})
+	})
+	return user, nil
+}
+
+// UpdateUser is
+func (c *CachedAPI) UpdateUser(user *models.UpdateUserRequest) (*models.User, error) {
+	updated, err := c.Client.UpdateUser(user)
+	if err == nil {
+		c.Invalidate(user.UserUUID)
+	}
+	return updated, err
+}
+
+// DeactivateUser is
+func (c *CachedAPI) DeactivateUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	defer c.Invalidate(userUUID)
+	return c.Client.DeactivateUser(userUUID, reason)
+}
+
+// ReactivateUser is
+func (c *CachedAPI) ReactivateUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	defer c.Invalidate(userUUID)
+	return c.Client.ReactivateUser(userUUID, reason)
+}
+
+// DeleteUser is
+func (c *CachedAPI) DeleteUser(userUUID uuid.UUID, gracePeriod time.Duration, purge bool) (*models.User, error) {
+	defer c.Invalidate(userUUID)
+	return c.Client.DeleteUser(userUUID, gracePeriod, purge)
+}
+
+// EraseUser is
+func (c *CachedAPI) EraseUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	defer c.Invalidate(userUUID)
+	return c.Client.EraseUser(userUUID, reason)
+}
+
+// ImportUsers invalidates the users of records with a uuid, also the ones
+// cached as not found, unless it is a dry run
+func (c *CachedAPI) ImportUsers(records iter.Seq2[*models.ImportRecord, error], dryRun bool) (*models.ImportResult, error) {
+	imported := make(map[uuid.UUID]struct{})
+	seen := func(yield func(*models.ImportRecord, error) bool) {
+		for record, err := range records {
+			if record != nil && record.User.UserUUID != uuid.Nil {
+				imported[record.User.UserUUID] = struct{}{}
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+
+	result, err := c.Client.ImportUsers(seen, dryRun)
+	if !dryRun {
+		c.invalidate(imported)
+	}
+	return result, err
+}
+
+// Invalidate drops the user, as read with any token, and every CheckAuth result of the user
+func (c *CachedAPI) Invalidate(userUUID uuid.UUID) {
+	c.invalidate(map[uuid.UUID]struct{}{userUUID: {}})
+}
+
+func (c *CachedAPI) invalidate(users map[uuid.UUID]struct{}) {
+	if len(users) == 0 {
+		return
+	}
+	// reads in flight are bumped before the entries go, so they cannot
+	// store their result after the removal
+	c.gens.bump(users)
+	c.users.removeFunc(func(key userKey, _ cachedUser) bool {
+		_, ok := users[key.userUUID]
+		return ok
+	})
+	c.auth.removeFunc(func(_ [sha256.Size]byte, e cachedUser) bool {
+		if e.user == nil {
+			return false
+		}
+		_, ok := users[e.user.UserUUID]
+		return ok
+	})
+}
+
+// generations tells reads whether the user they read was invalidated
+// during the call. Only users being read have a generation.
+type generations struct {
+	mu      sync.Mutex
+	any     uint64
+	reading map[uuid.UUID]*keyGeneration
+}
+
+type keyGeneration struct {
+	gen     uint64
+	readers int
+}
+
+// begin registers a read of the user and returns its generation, end must follow
+func (g *generations) begin(userUUID uuid.UUID) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	k, ok := g.reading[userUUID]
+	if !ok {
+		k = &keyGeneration{}
+		g.reading[userUUID] = k
+	}
+	k.readers++
+	return k.gen
+}
+
+// end runs store unless the user was invalidated since begin
+func (g *generations) end(userUUID uuid.UUID, gen uint64, store func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	k := g.reading[userUUID]
+	if k.gen == gen {
+		store()
+	}
+	if k.readers--; k.readers == 0 {
+		delete(g.reading, userUUID)
+	}
+}
+
+// current is the generation of every user together
+func (g *generations) current() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.any
+}
+
+// endAny runs store unless any user was invalidated since current
+func (g *generations) endAny(gen uint64, store func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.any == gen {
+		store()
+	}
+}
+
+func (g *generations) bump(users map[uuid.UUID]struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.any++
+	for userUUID := range users {
+		if k, ok := g.reading[userUUID]; ok {
+			k.gen++
+		}
+	}
+}
+
+func clone(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+	u := *user
+	return &u
+}
+
+// lru is a size bounded least recently used map safe for concurrent use
+type lru[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	return &lru[K, V]{
+		size:  size,
+		order: list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+func (l *lru[K, V]) get(key K) (V, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruEntry[K, V]).value, true
+}
+
+func (l *lru[K, V]) add(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		el.Value.(*lruEntry[K, V]).value = value
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+func (l *lru[K, V]) removeFunc(match func(K, V) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, el := range l.items {
+		if match(key, el.Value.(*lruEntry[K, V]).value) {
+			l.order.Remove(el)
+			delete(l.items, key)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+// fakeClient counts lookups, users missing from users are not found
+type fakeClient struct {
+	Client
+
+	mu      sync.Mutex
+	users   map[uuid.UUID]models.User
+	lookups int
+	checks  int
+}
+
+func newFakeClient(users ...models.User) *fakeClient {
+	c := &fakeClient{users: make(map[uuid.UUID]models.User)}
+	for _, u := range users {
+		c.users[u.UserUUID] = u
+	}
+	return c
+}
+
+func (c *fakeClient) WithContext(context.Context) Client {
+	return c
+}
+
+func (c *fakeClient) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups++
+	u, ok := c.users[userUUID]
+	if !ok {
+		return nil, fmt.Errorf("fake: %w", models.ErrUserNotFound)
+	}
+	return &u, nil
+}
+
+func (c *fakeClient) CheckAuth(token []byte) (*models.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks++
+	for _, u := range c.users {
+		if string(token) == u.Username {
+			return &u, nil
+		}
+	}
+	return nil, errors.New("fake: invalid token")
+}
+
+func (c *fakeClient) UpdateUser(req *models.UpdateUserRequest) (*models.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u := c.users[req.UserUUID]
+	if req.Email != nil {
+		u.Email = *req.Email
+	}
+	c.users[req.UserUUID] = u
+	return &u, nil
+}
+
+func (c *fakeClient) ImportUsers(records iter.Seq2[*models.ImportRecord, error], dryRun bool) (*models.ImportResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &models.ImportResult{DryRun: dryRun}
+	for record, err := range records {
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			c.users[record.User.UserUUID] = record.User
+		}
+		result.Created++
+	}
+	return result, nil
+}
+
+func (c *fakeClient) counts() (lookups, checks int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookups, c.checks
+}
+
+// newTestCache returns a cache over api whose clock is moved with the returned func
+func newTestCache(api Client, opts ...CacheOption) (*CachedAPI, func(time.Duration)) {
+	c := NewCache(api, opts...)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func testUser() models.User {
+	return models.User{UserUUID: uuid.Must(uuid.NewV4()), Username: "jane", Email: "jane@example.com"}
+}
+
+func TestCacheUserByUUIDExpires(t *testing.T) {
+	u := testUser()
+	api := newFakeClient(u)
+	c, advance := newTestCache(api, WithCacheTTL(time.Minute))
+
+	for range 3 {
+		if _, err := c.UserByUUID(u.UserUUID); err != nil {
+			t.Fatalf("UserByUUID: %v", err)
+		}
+	}
+	if lookups, _ := api.counts(); lookups != 1 {
+		t.Fatalf("%d lookups within the ttl, want 1", lookups)
+	}
+
+	advance(time.Minute)
+	if _, err := c.UserByUUID(u.UserUUID); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	if lookups, _ := api.counts(); lookups != 2 {
+		t.Fatalf("%d lookups after the ttl, want 2", lookups)
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	api := newFakeClient()
+	c, advance := newTestCache(api, WithCacheNegativeTTL(10*time.Second))
+	missing := uuid.Must(uuid.NewV4())
+
+	for range 2 {
+		if _, err := c.UserByUUID(missing); !errors.Is(err, models.ErrUserNotFound) {
+			t.Fatalf("UserByUUID error = %v, want ErrUserNotFound", err)
+		}
+	}
+	if lookups, _ := api.counts(); lookups != 1 {
+		t.Fatalf("%d lookups of a cached not found user, want 1", lookups)
+	}
+
+	advance(10 * time.Second)
+	_, _ = c.UserByUUID(missing)
+	if lookups, _ := api.counts(); lookups != 2 {
+		t.Fatalf("%d lookups after the negative ttl, want 2", lookups)
+	}
+}
+
+func TestCacheReturnsCopies(t *testing.T) {
+	u := testUser()
+	c, _ := newTestCache(newFakeClient(u))
+
+	first, _ := c.UserByUUID(u.UserUUID)
+	first.Email = "changed@example.com"
+	second, _ := c.UserByUUID(u.UserUUID)
+	second.Email = "again@example.com"
+	third, _ := c.UserByUUID(u.UserUUID)
+	if third.Email != u.Email {
+		t.Fatalf("cached user was changed through a returned copy: %q", third.Email)
+	}
+
+	auth, _ := c.CheckAuth([]byte("jane"))
+	auth.Email = "changed@example.com"
+	if auth, _ := c.CheckAuth([]byte("jane")); auth.Email != u.Email {
+		t.Fatalf("cached auth user was changed through a returned copy: %q", auth.Email)
+	}
+}
+
+func TestCacheKeepsCallerTokensApart(t *testing.T) {
+	u := testUser()
+	api := newFakeClient(u)
+	c, _ := newTestCache(api)
+	alice := c.WithContext(ContextWithToken(context.Background(), "alice-token"))
+	bob := c.WithContext(ContextWithToken(context.Background(), "bob-token"))
+
+	for _, client := range []Client{alice, alice, bob, bob} {
+		if _, err := client.UserByUUID(u.UserUUID); err != nil {
+			t.Fatalf("UserByUUID: %v", err)
+		}
+	}
+	if lookups, _ := api.counts(); lookups != 2 {
+		t.Fatalf("%d lookups for two callers, want 2", lookups)
+	}
+
+	c.Invalidate(u.UserUUID)
+	_, _ = alice.UserByUUID(u.UserUUID)
+	_, _ = bob.UserByUUID(u.UserUUID)
+	if lookups, _ := api.counts(); lookups != 4 {
+		t.Fatalf("%d lookups after Invalidate, want 4", lookups)
+	}
+}
+
+func TestCacheUpdateInvalidates(t *testing.T) {
+	u := testUser()
+	api := newFakeClient(u)
+	c, _ := newTestCache(api)
+
+	_, _ = c.UserByUUID(u.UserUUID)
+	_, _ = c.CheckAuth([]byte("jane"))
+	email := "new@example.com"
+	if _, err := c.UpdateUser(&models.UpdateUserRequest{UserUUID: u.UserUUID, Email: &email}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	got, _ := c.UserByUUID(u.UserUUID)
+	auth, _ := c.CheckAuth([]byte("jane"))
+	if got.Email != email || auth.Email != email {
+		t.Fatalf("stale users after UpdateUser: %q, %q", got.Email, auth.Email)
+	}
+	if lookups, checks := api.counts(); lookups != 2 || checks != 2 {
+		t.Fatalf("lookups %d, checks %d after UpdateUser, want 2 and 2", lookups, checks)
+	}
+}
+
+// slowClient holds UserByUUID and CheckAuth between reading the user and
+// returning it until release is closed, each call is sent on read
+type slowClient struct {
+	*fakeClient
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (c *slowClient) WithContext(context.Context) Client {
+	return c
+}
+
+func (c *slowClient) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
+	u, err := c.fakeClient.UserByUUID(userUUID)
+	c.read <- struct{}{}
+	<-c.release
+	return u, err
+}
+
+func (c *slowClient) CheckAuth(token []byte) (*models.User, error) {
+	u, err := c.fakeClient.CheckAuth(token)
+	c.read <- struct{}{}
+	<-c.release
+	return u, err
+}
+
+// TestCacheReadDuringUpdate checks a read that got the user before an
+// UpdateUser does not cache it after the update invalidated the user
+func TestCacheReadDuringUpdate(t *testing.T) {
+	u := testUser()
+	api := newFakeClient(u)
+	slow := &slowClient{fakeClient: api, read: make(chan struct{}, 4), release: make(chan struct{})}
+	c, _ := newTestCache(slow)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = c.UserByUUID(u.UserUUID)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = c.CheckAuth([]byte("jane"))
+	}()
+	<-slow.read
+	<-slow.read
+
+	email := "new@example.com"
+	if _, err := c.UpdateUser(&models.UpdateUserRequest{UserUUID: u.UserUUID, Email: &email}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	close(slow.release)
+	wg.Wait()
+
+	got, _ := c.UserByUUID(u.UserUUID)
+	auth, _ := c.CheckAuth([]byte("jane"))
+	if got.Email != email || auth.Email != email {
+		t.Fatalf("stale users cached by reads overlapping UpdateUser: %q, %q", got.Email, auth.Email)
+	}
+	if n := len(c.gens.reading); n != 0 {
+		t.Fatalf("%d generations left after the reads ended", n)
+	}
+}
+
+func TestCacheImportInvalidates(t *testing.T) {
+	api := newFakeClient()
+	c, _ := newTestCache(api)
+	u := testUser()
+	records := func(yield func(*models.ImportRecord, error) bool) {
+		yield(&models.ImportRecord{User: u}, nil)
+	}
+
+	if _, err := c.UserByUUID(u.UserUUID); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("UserByUUID error = %v, want ErrUserNotFound", err)
+	}
+	if _, err := c.ImportUsers(records, true); err != nil {
+		t.Fatalf("dry run ImportUsers: %v", err)
+	}
+	if _, err := c.UserByUUID(u.UserUUID); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("UserByUUID after a dry run error = %v, want ErrUserNotFound", err)
+	}
+
+	if _, err := c.ImportUsers(records, false); err != nil {
+		t.Fatalf("ImportUsers: %v", err)
+	}
+	if _, err := c.UserByUUID(u.UserUUID); err != nil {
+		t.Fatalf("UserByUUID after import: %v", err)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	users := []models.User{testUser(), testUser(), testUser()}
+	api := newFakeClient(users...)
+	c, _ := newTestCache(api, WithCacheSize(2))
+
+	_, _ = c.UserByUUID(users[0].UserUUID)
+	_, _ = c.UserByUUID(users[1].UserUUID)
+	_, _ = c.UserByUUID(users[0].UserUUID)
+	_, _ = c.UserByUUID(users[2].UserUUID) // evicts users[1]
+	_, _ = c.UserByUUID(users[0].UserUUID)
+	if lookups, _ := api.counts(); lookups != 3 {
+		t.Fatalf("%d lookups, want 3", lookups)
+	}
+	_, _ = c.UserByUUID(users[1].UserUUID)
+	if lookups, _ := api.counts(); lookups != 4 {
+		t.Fatalf("%d lookups after eviction, want 4", lookups)
+	}
+}
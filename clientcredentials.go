@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+	"google.golang.org/grpc/credentials"
+)
+
+// serviceTokenRefreshBefore is how long before expiry a service token is renewed,
+// capped at half of the token lifetime
+const serviceTokenRefreshBefore = 30 * time.Second
+
+// WithClientCredentials authenticates every call with a service token issued
+// for the service client. The token is fetched on the first call and renewed
+// shortly before it expires.
+func WithClientCredentials(clientID string, secret []byte, scopes ...string) Option {
+	return func(api *UsersAPI) {
+		api.perRPCCredentials = append(api.perRPCCredentials, &clientCredentials{
+			api:      api,
+			clientID: clientID,
+			secret:   secret,
+			scopes:   scopes,
+			now:      time.Now,
+		})
+	}
+}
+
+// clientCredentials keeps a service token fresh. It holds the client built by
+// New, so the token is requested over the same connection, outside of the
+// client limits and circuit breaker: a call holding an in-flight slot may
+// be waiting for the token.
+type clientCredentials struct {
+	api      *UsersAPI
+	clientID string
+	secret   []byte
+	scopes   []string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+	expiresAt time.Time
+	// fetch is the token request in flight, shared by every caller
+	fetch *tokenFetch
+}
+
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func (c *clientCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	// the token request itself is authenticated with the secret. Health
+	// checks go out before the connection is ready and must not wait on it.
+	if ri, ok := credentials.RequestInfoFromContext(ctx); ok &&
+		(ri.Method == proto.UserService_ClientCredentialsToken_FullMethodName ||
+			!strings.HasPrefix(ri.Method, "/"+proto.UserService_ServiceDesc.ServiceName+"/")) {
+		return nil, nil
+	}
+
+	token, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{authorizationKey: "Bearer " + token}, nil
+}
+
+func (*clientCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// get returns the cached token. Past refreshAt a new token is fetched in the
+// background while the current one is still used, after expiry callers wait
+// for the fetch.
+func (c *clientCredentials) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	now := c.now()
+	token, valid := c.token, c.token != "" && now.Before(c.expiresAt)
+	if valid && now.Before(c.refreshAt) {
+		c.mu.Unlock()
+		return token, nil
+	}
+	f := c.fetch
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		c.fetch = f
+		go c.refresh(f)
+	}
+	c.mu.Unlock()
+
+	if valid {
+		return token, nil
+	}
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refresh requests a token for every caller of get, a failure is
+// returned to the callers waiting and retried by the next get
+func (c *clientCredentials) refresh(f *tokenFetch) {
+	ctx, cancel := context.WithTimeout(withoutClientLimits(c.api.context()), c.api.timeout)
+	defer cancel()
+	t, err := c.api.clientCredentialsToken(ctx, c.clientID, c.secret, c.scopes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetch = nil
+	if err != nil {
+		f.err = err
+	} else {
+		now := c.now()
+		c.token = string(t.Token)
+		c.expiresAt = t.ExpiresAt
+		c.refreshAt = t.ExpiresAt.Add(-min(serviceTokenRefreshBefore, t.ExpiresAt.Sub(now)/2))
+		f.token = c.token
+	}
+	close(f.done)
+}
+
+type clientLimitsKey struct{}
+
+// withoutClientLimits marks calls the rate limits and circuit breaker let through
+func withoutClientLimits(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientLimitsKey{}, true)
+}
+
+func skipsClientLimits(ctx context.Context) bool {
+	skip, _ := ctx.Value(clientLimitsKey{}).(bool)
+	return skip
+}
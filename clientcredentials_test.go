@@ -0,0 +1,193 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// tokenClient answers ClientCredentialsToken with numbered tokens
+type tokenClient struct {
+	proto.UserServiceClient
+
+	clock *fakeClock
+	ttl   time.Duration
+	calls atomic.Int64
+	fail  atomic.Bool
+	// block holds every token request until it is closed
+	block chan struct{}
+}
+
+func (c *tokenClient) ClientCredentialsToken(ctx context.Context, req *proto.ClientCredentialsRequest,
+	_ ...grpc.CallOption) (*proto.ServiceToken, error) {
+	n := c.calls.Add(1)
+	if c.block != nil {
+		<-c.block
+	}
+	if c.fail.Load() {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	return &proto.ServiceToken{
+		Token:     []byte("token-" + string(rune('0'+n))),
+		ExpiresAt: timestamppb.New(c.clock.Now().Add(c.ttl)),
+	}, nil
+}
+
+func newTestCredentials(client *tokenClient) *clientCredentials {
+	api := &UsersAPI{UserServiceClient: client, timeout: time.Second}
+	return &clientCredentials{api: api, clientID: "svc", secret: []byte("secret"), now: client.clock.Now}
+}
+
+func newTokenClient(ttl time.Duration) *tokenClient {
+	return &tokenClient{clock: &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, ttl: ttl}
+}
+
+func getToken(t *testing.T, c *clientCredentials) string {
+	t.Helper()
+	token, err := c.get(context.Background())
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	return token
+}
+
+func TestClientCredentialsCachesToken(t *testing.T) {
+	client := newTokenClient(time.Hour)
+	client.block = make(chan struct{})
+	c := newTestCredentials(client)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], _ = c.get(context.Background())
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(client.block)
+	wg.Wait()
+
+	for _, token := range tokens {
+		if token != "token-1" {
+			t.Fatalf("tokens = %v, want token-1 for every caller", tokens)
+		}
+	}
+	if getToken(t, c) != "token-1" || client.calls.Load() != 1 {
+		t.Fatalf("got %d token requests, want 1", client.calls.Load())
+	}
+}
+
+func TestClientCredentialsRefreshesEarly(t *testing.T) {
+	client := newTokenClient(10 * time.Minute)
+	c := newTestCredentials(client)
+	getToken(t, c)
+
+	// within the refresh window the current token is used while a new one is fetched
+	client.clock.Advance(10*time.Minute - serviceTokenRefreshBefore + time.Second)
+	if token := getToken(t, c); token != "token-1" {
+		t.Fatalf("got %s during the refresh, want the current token", token)
+	}
+	deadline := time.Now().Add(time.Second)
+	for getToken(t, c) != "token-2" {
+		if time.Now().After(deadline) {
+			t.Fatal("token not refreshed before expiry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := client.calls.Load(); n != 2 {
+		t.Fatalf("got %d token requests, want 2", n)
+	}
+}
+
+func TestClientCredentialsError(t *testing.T) {
+	client := newTokenClient(time.Minute)
+	client.fail.Store(true)
+	c := newTestCredentials(client)
+
+	if _, err := c.get(context.Background()); status.Code(errors.Unwrap(err)) != codes.Unavailable {
+		t.Fatalf("got %v, want the token request error", err)
+	}
+
+	// failures are not cached
+	client.fail.Store(false)
+	if token := getToken(t, c); token != "token-2" {
+		t.Fatalf("got %s after a failure, want a new token", token)
+	}
+
+	// an expired token is not used while the refresh fails
+	client.fail.Store(true)
+	client.clock.Advance(2 * time.Minute)
+	if _, err := c.get(context.Background()); err == nil {
+		t.Fatal("expired token returned")
+	}
+}
+
+type credentialsServer struct {
+	testServer
+	authorized atomic.Int64
+}
+
+func (s *credentialsServer) ClientCredentialsToken(context.Context, *proto.ClientCredentialsRequest) (*proto.ServiceToken, error) {
+	// slow enough for every outer call to take its in-flight slot first
+	time.Sleep(50 * time.Millisecond)
+	return &proto.ServiceToken{Token: []byte("service"), ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))}, nil
+}
+
+func (s *credentialsServer) UserBy(ctx context.Context, req *proto.UserGetter) (*proto.User, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(authorizationKey); len(v) == 1 && v[0] == "Bearer service" {
+		s.authorized.Add(1)
+	}
+	return s.testServer.UserBy(ctx, req)
+}
+
+// TestClientCredentialsBypassLimits checks the token request does not wait
+// for an in-flight slot held by the calls waiting for the token
+func TestClientCredentialsBypassLimits(t *testing.T) {
+	srv := &credentialsServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr,
+		WithClientCredentials("svc", []byte("secret")),
+		WithInsecure(),
+		WithRateLimit("", LimitConfig{MaxInFlight: 2}),
+		WithCircuitBreaker(BreakerConfig{}),
+		WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := api.UserByUUID(newUUIDs(1)[0])
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UserByUUID: %v", err)
+		}
+	}
+	if n := srv.authorized.Load(); n != 2 {
+		t.Fatalf("%d calls carried the service token, want 2", n)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	user "github.com/garden-raccoon/user-pkg"
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+func runGet(a *app, args []string) error {
+	fs := newFlagSet("get")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errUsage
+	}
+
+	var u *models.User
+	if id, err := uuid.FromString(rest[0]); err == nil {
+		u, err = a.api.UserByUUID(id)
+		if err != nil {
+			return err
+		}
+	} else {
+		u, err = a.api.UserByEmail(rest[0])
+		if err != nil {
+			return err
+		}
+	}
+	return a.printUsers([]*models.User{u})
+}
+
+func runCreate(a *app, args []string) error {
+	fs := newFlagSet("create")
+	id := fs.String("uuid", "", "uuid of the user, generated when empty")
+	email := fs.String("email", "", "email")
+	username := fs.String("username", "", "username")
+	firstName := fs.String("first-name", "", "first name")
+	lastName := fs.String("last-name", "", "last name")
+	avatar := fs.String("avatar", "", "avatar url")
+	userType := fs.Int("type", 0, "user type")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 || *email == "" {
+		return errUsage
+	}
+
+	u := &models.User{
+		Email:     *email,
+		Username:  *username,
+		FirstName: *firstName,
+		LastName:  *lastName,
+		Avatar:    *avatar,
+		UserType:  *userType,
+	}
+	if *id == "" {
+		if u.UserUUID, err = uuid.NewV4(); err != nil {
+			return fmt.Errorf("generate uuid: %w", err)
+		}
+	} else if u.UserUUID, err = uuid.FromString(*id); err != nil {
+		return fmt.Errorf("invalid uuid: %w", err)
+	}
+
+	if err := a.api.CreateUser(u); err != nil {
+		return err
+	}
+	return a.printUsers([]*models.User{u})
+}
+
+func runUpdate(a *app, args []string) error {
+	fs := newFlagSet("update")
+	fs.String("email", "", "new email")
+	fs.String("username", "", "new username")
+	fs.String("first-name", "", "new first name")
+	fs.String("last-name", "", "new last name")
+	fs.String("avatar", "", "new avatar url")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errUsage
+	}
+	id, err := uuid.FromString(rest[0])
+	if err != nil {
+		return fmt.Errorf("invalid uuid: %w", err)
+	}
+
+	// only flags given on the command line are changed. An empty value is
+	// sent as unset, so fields cannot be cleared and -flag="" is rejected.
+	req := &models.UpdateUserRequest{UserUUID: id}
+	fields := map[string]**string{
+		"email":      &req.Email,
+		"username":   &req.Username,
+		"first-name": &req.FirstName,
+		"last-name":  &req.LastName,
+		"avatar":     &req.Avatar,
+	}
+	var empty []string
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		if v == "" {
+			empty = append(empty, "-"+f.Name)
+			return
+		}
+		*fields[f.Name] = &v
+	})
+	if len(empty) > 0 {
+		return fmt.Errorf("%s: empty values cannot clear a field", strings.Join(empty, ", "))
+	}
+	if fs.NFlag() == 0 {
+		return errUsage
+	}
+
+	u, err := a.api.UpdateUser(req)
+	if err != nil {
+		return err
+	}
+	return a.printUsers([]*models.User{u})
+}
+
+func runSignIn(a *app, args []string) error {
+	fs := newFlagSet("signin")
+	email := fs.String("email", "", "email")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 || *email == "" {
+		return errUsage
+	}
+
+	// the password is never a flag, so it does not end up in the shell history
+	password, err := readLine(a.stdin)
+	if err != nil {
+		return fmt.Errorf("read password: %w", err)
+	}
+	token, err := a.api.SignIn(*email, []byte(password))
+	if err != nil {
+		return err
+	}
+	return a.printToken(token)
+}
+
+func runCheckToken(a *app, args []string) error {
+	fs := newFlagSet("check-token")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 {
+		// a token argument would end up in the shell history and the process list
+		return errUsage
+	}
+	token, err := readLine(a.stdin)
+	if err != nil {
+		return fmt.Errorf("read token: %w", err)
+	}
+
+	u, err := a.api.CheckAuth([]byte(token))
+	if err != nil {
+		return err
+	}
+	return a.printUsers([]*models.User{u})
+}
+
+func runHealth(a *app, args []string) error {
+	fs := newFlagSet("health")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errUsage
+	}
+
+	state := "SERVING"
+	err = a.api.HealthCheck()
+	if err != nil {
+		if !errors.Is(err, user.ErrUnhealthy) {
+			return err
+		}
+		state = "NOT_SERVING"
+	}
+	if perr := a.printHealth(state); perr != nil {
+		return perr
+	}
+	return err
+}
+
+func runList(a *app, args []string) error {
+	fs := newFlagSet("list")
+	query := fs.String("query", "", "prefix of email, username, first or last name")
+	types := fs.String("type", "", "comma separated user types")
+	statuses := fs.String("status", "", "comma separated statuses: active, deactivated, deleted")
+	createdAfter := fs.String("created-after", "", "RFC 3339 time")
+	createdBefore := fs.String("created-before", "", "RFC 3339 time")
+	limit := fs.Int("limit", 100, "max number of users, 0 lists all")
+	pageSize := fs.Int("page-size", 0, "users per request, the server default when 0")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errUsage
+	}
+
+	filter := models.ListUsersFilter{Query: *query, PageSize: *pageSize}
+	for _, t := range splitList(*types) {
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return fmt.Errorf("invalid user type %q", t)
+		}
+		filter.UserTypes = append(filter.UserTypes, n)
+	}
+	for _, s := range splitList(*statuses) {
+		st, err := models.ParseUserStatus(s)
+		if err != nil {
+			return err
+		}
+		filter.Statuses = append(filter.Statuses, st)
+	}
+	if filter.CreatedAfter, err = parseTime(*createdAfter); err != nil {
+		return err
+	}
+	if filter.CreatedBefore, err = parseTime(*createdBefore); err != nil {
+		return err
+	}
+
+	var users []*models.User
+	for u, err := range user.AllUsers(a.api, filter) {
+		if err != nil {
+			return err
+		}
+		users = append(users, u)
+		if *limit > 0 && len(users) >= *limit {
+			break
+		}
+	}
+	return a.printUsers(users)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseInterspersed parses flags given before and after the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// readLine reads the first line of r without its line ending
+func readLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+	}
+	return t, nil
+}
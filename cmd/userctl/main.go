@@ -0,0 +1,181 @@
+// Command userctl inspects and administers users through the user service.
+//
+// Usage:
+//
+//	userctl [flags] <command> [command flags] [args]
+//
+// Commands are get, create, update, signin, check-token, health and list.
+// Connection flags mirror the client options, USERCTL_ADDR and
+// USERCTL_TOKEN set the address and the bearer token sent with every call.
+// The token is only sent over TLS, -plaintext allows it on a local server.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	user "github.com/garden-raccoon/user-pkg"
+)
+
+const (
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage makes main print the usage of the command and exit with exitUsage
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(app *app, args []string) error
+}
+
+var commands = []command{
+	{"get", "<uuid|email>", "show a user", runGet},
+	{"create", "-email <email> [flags]", "create a user", runCreate},
+	{"update", "<uuid> [flags]", "change the given fields of a user", runUpdate},
+	{"signin", "-email <email>", "sign in with the password read from stdin and print the token", runSignIn},
+	{"check-token", "", "show the user of the token read from stdin", runCheckToken},
+	{"health", "", "check the serving state", runHealth},
+	{"list", "[flags]", "list users", runList},
+}
+
+// app holds the global flags and the client shared by the commands
+type app struct {
+	addr          string
+	timeout       time.Duration
+	token         string
+	tls           bool
+	tlsCA         string
+	tlsServerName string
+	tlsInsecure   bool
+	plaintext     bool
+	output        string
+
+	stdin  io.Reader
+	stdout io.Writer
+	api    user.Client
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout}
+
+	fs := flag.NewFlagSet("userctl", flag.ContinueOnError)
+	fs.StringVar(&a.addr, "addr", envOr("USERCTL_ADDR", "localhost:9090"), "user service address")
+	fs.DurationVar(&a.timeout, "timeout", 10*time.Second, "deadline of every call")
+	fs.StringVar(&a.token, "token", os.Getenv("USERCTL_TOKEN"), "bearer token sent with every call")
+	fs.BoolVar(&a.tls, "tls", false, "dial with TLS")
+	fs.StringVar(&a.tlsCA, "tls-ca", "", "PEM file of the CA verifying the server, implies -tls")
+	fs.StringVar(&a.tlsServerName, "tls-server-name", "", "server name verified instead of the host of -addr")
+	fs.BoolVar(&a.tlsInsecure, "tls-insecure", false, "skip verifying the server certificate")
+	fs.BoolVar(&a.plaintext, "plaintext", false, "send -token without TLS, for local development")
+	fs.StringVar(&a.output, "o", "table", "output format, table or json")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	secure := a.tls || a.tlsCA != "" || a.tlsInsecure
+	if fs.NArg() == 0 || (a.output != "table" && a.output != "json") || (a.plaintext && secure) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := a.connect(); err != nil {
+			fmt.Fprintln(os.Stderr, "userctl:", err)
+			return exitError
+		}
+		defer a.api.Close()
+
+		if err := c.run(a, fs.Args()[1:]); err != nil {
+			if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(os.Stderr, "usage: userctl %s %s\n", c.name, c.args)
+				return exitUsage
+			}
+			fmt.Fprintln(os.Stderr, "userctl:", err)
+			return exitError
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "userctl: unknown command %q\n", name)
+	fs.Usage()
+	return exitUsage
+}
+
+func (a *app) connect() error {
+	opts := []user.Option{user.WithTimeout(a.timeout)}
+	if a.token != "" {
+		opts = append(opts, user.WithStaticToken(a.token))
+	}
+	if a.plaintext {
+		opts = append(opts, user.WithInsecure())
+	}
+	if a.tls || a.tlsCA != "" || a.tlsInsecure {
+		cfg, err := a.tlsConfig()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, user.WithTLS(cfg))
+	}
+
+	api, err := user.NewClient(a.addr, opts...)
+	if err != nil {
+		return fmt.Errorf("connect %s: %w", a.addr, err)
+	}
+	a.api = api
+	return nil
+}
+
+func (a *app) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         a.tlsServerName,
+		InsecureSkipVerify: a.tlsInsecure,
+	}
+	if a.tlsCA != "" {
+		pem, err := os.ReadFile(a.tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("read ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("read ca: no certificates in %s", a.tlsCA)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "usage: userctl [flags] <command> [command flags] [args]")
+	fmt.Fprintln(out, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	fs.PrintDefaults()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	user "github.com/garden-raccoon/user-pkg"
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+var alice = &models.User{
+	UserUUID:  uuid.Must(uuid.FromString("6ba7b810-9dad-11d1-80b4-00c04fd430c8")),
+	Email:     "alice@example.com",
+	Username:  "alice",
+	FirstName: "Alice",
+	LastName:  "Liddell",
+	Status:    models.StatusActive,
+	CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+// fakeAPI answers the calls of the commands, the embedded Client is nil
+type fakeAPI struct {
+	user.Client
+
+	byEmail  string
+	token    string
+	password string
+	update   *models.UpdateUserRequest
+	filter   models.ListUsersFilter
+	pages    int
+	health   error
+}
+
+func (f *fakeAPI) UserByUUID(id uuid.UUID) (*models.User, error) {
+	if id != alice.UserUUID {
+		return nil, models.ErrUserNotFound
+	}
+	return alice, nil
+}
+
+func (f *fakeAPI) UserByEmail(email string) (*models.User, error) {
+	f.byEmail = email
+	return alice, nil
+}
+
+func (f *fakeAPI) UpdateUser(req *models.UpdateUserRequest) (*models.User, error) {
+	f.update = req
+	return alice, nil
+}
+
+func (f *fakeAPI) CheckAuth(token []byte) (*models.User, error) {
+	f.token = string(token)
+	return alice, nil
+}
+
+func (f *fakeAPI) SignIn(email string, password []byte) ([]byte, error) {
+	f.password = string(password)
+	return []byte("signed-token"), nil
+}
+
+func (f *fakeAPI) ListUsers(filter models.ListUsersFilter, cursor string) (*models.UsersPage, error) {
+	f.filter = filter
+	f.pages++
+	page := &models.UsersPage{Users: []*models.User{alice, alice}}
+	if f.pages < 10 {
+		page.NextCursor = cursor + "x"
+	}
+	return page, nil
+}
+
+func (f *fakeAPI) HealthCheck() error {
+	return f.health
+}
+
+func newTestApp(stdin string) (*app, *fakeAPI, *bytes.Buffer) {
+	api := &fakeAPI{}
+	out := &bytes.Buffer{}
+	return &app{output: "table", stdin: strings.NewReader(stdin), stdout: out, api: api}, api, out
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"-o", "yaml", "get", "x"},
+		{"-plaintext", "-tls", "health"},
+		{"frobnicate"},
+	}
+	for _, args := range tests {
+		if code := run(args); code != exitUsage {
+			t.Errorf("run(%q) = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	a, api, out := newTestApp("")
+	if err := runGet(a, []string{alice.UserUUID.String()}); err != nil {
+		t.Fatalf("get by uuid: %v", err)
+	}
+	if !strings.Contains(out.String(), "alice@example.com") {
+		t.Fatalf("output %q", out)
+	}
+	if err := runGet(a, []string{"alice@example.com"}); err != nil || api.byEmail != "alice@example.com" {
+		t.Fatalf("get by email: %v, looked up %q", err, api.byEmail)
+	}
+	if err := runGet(a, nil); !errors.Is(err, errUsage) {
+		t.Fatalf("get without argument: %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	a, api, _ := newTestApp("")
+	// flags are accepted after the uuid
+	if err := runUpdate(a, []string{alice.UserUUID.String(), "-first-name", "Al"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	req := api.update
+	if req.UserUUID != alice.UserUUID || req.FirstName == nil || *req.FirstName != "Al" {
+		t.Fatalf("request = %+v", req)
+	}
+	if req.Email != nil || req.Username != nil || req.LastName != nil || req.Avatar != nil {
+		t.Fatalf("fields not given were sent: %+v", req)
+	}
+
+	api.update = nil
+	err := runUpdate(a, []string{"-last-name=", alice.UserUUID.String()})
+	if err == nil || !strings.Contains(err.Error(), "-last-name") || api.update != nil {
+		t.Fatalf("empty value: %v, sent %+v", err, api.update)
+	}
+	if err := runUpdate(a, []string{alice.UserUUID.String()}); !errors.Is(err, errUsage) {
+		t.Fatalf("update without flags: %v", err)
+	}
+	if err := runUpdate(a, []string{"not-a-uuid", "-username", "x"}); err == nil {
+		t.Fatal("update accepted an invalid uuid")
+	}
+}
+
+func TestCheckTokenReadsStdin(t *testing.T) {
+	a, api, _ := newTestApp("secret-token\r\n")
+	if err := runCheckToken(a, nil); err != nil {
+		t.Fatalf("check-token: %v", err)
+	}
+	if api.token != "secret-token" {
+		t.Fatalf("checked %q, want the line read from stdin", api.token)
+	}
+
+	a, _, _ = newTestApp("")
+	if err := runCheckToken(a, []string{"secret-token"}); !errors.Is(err, errUsage) {
+		t.Fatalf("token argument: %v, want a usage error", err)
+	}
+	if err := runCheckToken(a, nil); err == nil {
+		t.Fatal("check-token without input succeeded")
+	}
+}
+
+func TestSignInReadsPassword(t *testing.T) {
+	a, api, out := newTestApp("hunter22")
+	if err := runSignIn(a, []string{"-email", "alice@example.com"}); err != nil {
+		t.Fatalf("signin: %v", err)
+	}
+	if api.password != "hunter22" || out.String() != "signed-token\n" {
+		t.Fatalf("password %q, output %q", api.password, out)
+	}
+}
+
+func TestList(t *testing.T) {
+	a, api, out := newTestApp("")
+	err := runList(a, []string{"-status", "active, Deactivated", "-type", "1,2", "-limit", "3",
+		"-created-after", "2024-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	f := api.filter
+	if len(f.Statuses) != 2 || f.Statuses[1] != models.StatusDeactivated || len(f.UserTypes) != 2 || f.CreatedAfter.IsZero() {
+		t.Fatalf("filter = %+v", f)
+	}
+	if api.pages != 2 {
+		t.Fatalf("requested %d pages for a limit of 3", api.pages)
+	}
+	if rows := strings.Count(out.String(), "\n"); rows != 4 {
+		t.Fatalf("got %d lines, want a header and 3 users:\n%s", rows, out)
+	}
+
+	for _, args := range [][]string{{"-status", "banned"}, {"-type", "admin"}, {"-created-before", "yesterday"}} {
+		if err := runList(a, args); err == nil {
+			t.Errorf("list %q succeeded", args)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	a, api, out := newTestApp("")
+	api.health = errors.New("node is NOT_SERVING: " + user.ErrUnhealthy.Error())
+	if err := runHealth(a, nil); err == nil {
+		t.Fatal("health of an unreachable service succeeded")
+	}
+
+	api.health = user.ErrUnhealthy
+	if err := runHealth(a, nil); !errors.Is(err, user.ErrUnhealthy) || out.String() != "NOT_SERVING\n" {
+		t.Fatalf("unhealthy: %v, output %q", err, out)
+	}
+}
+
+func TestPrintUsers(t *testing.T) {
+	a, _, out := newTestApp("")
+	deleted := *alice
+	deleted.FirstName, deleted.LastName, deleted.Username = "", "", ""
+	deleted.CreatedAt = time.Time{}
+	if err := a.printUsers([]*models.User{alice, &deleted}); err != nil {
+		t.Fatalf("printUsers: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "UUID") {
+		t.Fatalf("table:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "Alice Liddell") || !strings.Contains(lines[1], "2024-01-02T03:04:05Z") {
+		t.Fatalf("row %q", lines[1])
+	}
+	dashes := 0
+	for _, field := range strings.Fields(lines[2]) {
+		if field == "-" {
+			dashes++
+		}
+	}
+	if dashes != 3 {
+		t.Fatalf("empty fields not shown as dashes: %q", lines[2])
+	}
+
+	a.output = "json"
+	out.Reset()
+	if err := a.printUsers([]*models.User{alice}); err != nil {
+		t.Fatalf("printUsers: %v", err)
+	}
+	var v map[string]any
+	if err := json.Unmarshal(out.Bytes(), &v); err != nil {
+		t.Fatalf("single user is not a JSON object: %v\n%s", err, out)
+	}
+	if v["uuid"] != alice.UserUUID.String() || v["status"] != "active" || v["first_name"] != "Alice" {
+		t.Fatalf("json = %v", v)
+	}
+	if _, ok := v["purge_at"]; ok {
+		t.Fatalf("zero purge_at in %v", v)
+	}
+
+	out.Reset()
+	if err := a.printUsers([]*models.User{alice, alice}); err != nil {
+		t.Fatalf("printUsers: %v", err)
+	}
+	var list []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil || len(list) != 2 {
+		t.Fatalf("users are not a JSON array: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+)
+
+// userView is the JSON form of a user
+type userView struct {
+	UUID      string     `json:"uuid"`
+	Email     string     `json:"email"`
+	Username  string     `json:"username,omitempty"`
+	FirstName string     `json:"first_name,omitempty"`
+	LastName  string     `json:"last_name,omitempty"`
+	Avatar    string     `json:"avatar,omitempty"`
+	Type      int        `json:"type"`
+	Status    string     `json:"status"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+func newUserView(u *models.User) userView {
+	v := userView{
+		UUID:      u.UserUUID.String(),
+		Email:     u.Email,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Avatar:    u.Avatar,
+		Type:      u.UserType,
+		Status:    u.Status.String(),
+	}
+	if !u.CreatedAt.IsZero() {
+		v.CreatedAt = &u.CreatedAt
+	}
+	if !u.PurgeAt.IsZero() {
+		v.PurgeAt = &u.PurgeAt
+	}
+	return v
+}
+
+func (a *app) printUsers(users []*models.User) error {
+	if a.output == "json" {
+		views := make([]userView, 0, len(users))
+		for _, u := range users {
+			views = append(views, newUserView(u))
+		}
+		if len(views) == 1 {
+			return a.printJSON(views[0])
+		}
+		return a.printJSON(views)
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tEMAIL\tUSERNAME\tNAME\tTYPE\tSTATUS\tCREATED")
+	for _, u := range users {
+		v := newUserView(u)
+		created := "-"
+		if v.CreatedAt != nil {
+			created = v.CreatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", v.UUID, v.Email, dash(v.Username),
+			dash(joinName(v.FirstName, v.LastName)), v.Type, v.Status, created)
+	}
+	return tw.Flush()
+}
+
+func (a *app) printToken(token []byte) error {
+	if a.output == "json" {
+		return a.printJSON(map[string]string{"token": string(token)})
+	}
+	_, err := fmt.Fprintln(a.stdout, string(token))
+	return err
+}
+
+func (a *app) printHealth(state string) error {
+	if a.output == "json" {
+		return a.printJSON(map[string]string{"status": state})
+	}
+	_, err := fmt.Fprintln(a.stdout, state)
+	return err
+}
+
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func joinName(first, last string) string {
+	switch {
+	case first == "":
+		return last
+	case last == "":
+		return first
+	}
+	return first + " " + last
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
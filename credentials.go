@@ -0,0 +1,164 @@
+package user
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/garden-raccoon/user-pkg/internal/bearer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationKey = "authorization"
+	// RequestIDKey is the metadata key the request id is sent with
+	RequestIDKey = "x-request-id"
+)
+
+// WithPerRPCCredentials attaches credentials to every call
+func WithPerRPCCredentials(creds credentials.PerRPCCredentials) Option {
+	return func(api *UsersAPI) {
+		api.perRPCCredentials = append(api.perRPCCredentials, creds)
+	}
+}
+
+// WithTLS dials with TLS, a nil config uses the system roots.
+// The client dials without TLS by default.
+func WithTLS(cfg *tls.Config) Option {
+	return func(api *UsersAPI) {
+		api.transportCredentials = credentials.NewTLS(cfg)
+	}
+}
+
+// WithInsecure allows per-call credentials, e.g. WithStaticToken, to be sent
+// on a connection without TLS. Only meant for local development and tests.
+func WithInsecure() Option {
+	return func(api *UsersAPI) {
+		api.allowInsecure = true
+	}
+}
+
+// WithStaticToken sends token as the bearer token of every call, e.g. a service token
+func WithStaticToken(token string) Option {
+	return WithPerRPCCredentials(staticToken(token))
+}
+
+// WithContextToken forwards the token of the caller, set with ContextWithToken
+// or taken from the incoming gRPC metadata, to calls made through WithContext
+func WithContextToken() Option {
+	return WithPerRPCCredentials(contextToken{})
+}
+
+// WithContext returns a client whose calls are made with ctx as parent, so
+// its deadline, token and request id apply. The connection is shared.
+func (api *UsersAPI) WithContext(ctx context.Context) Client {
+	c := *api
+	c.ctx = ctx
+	return &c
+}
+
+func (api *UsersAPI) context() context.Context {
+	if api.ctx == nil {
+		return context.Background()
+	}
+	return api.ctx
+}
+
+type tokenKey struct{}
+
+type requestIDKey struct{}
+
+// ContextWithToken returns a copy of ctx carrying the bearer token for WithContextToken
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id sent with every call
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request id set by ContextWithRequestID
+// or received in the incoming gRPC metadata
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// tokenFromContext returns the token set by ContextWithToken
+// or the bearer token of the incoming gRPC metadata
+func tokenFromContext(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenKey{}).(string); ok && token != "" {
+		return token
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get(authorizationKey) {
+			if token, ok := bearer.Token(v); ok {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+type staticToken string
+
+func (t staticToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + string(t)}, nil
+}
+
+func (staticToken) RequireTransportSecurity() bool {
+	return true
+}
+
+type contextToken struct{}
+
+func (contextToken) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	if token := tokenFromContext(ctx); token != "" {
+		return map[string]string{authorizationKey: "Bearer " + token}, nil
+	}
+	return nil, nil
+}
+
+func (contextToken) RequireTransportSecurity() bool {
+	return true
+}
+
+// insecureCredentials lets the wrapped credentials go over plaintext, see WithInsecure
+type insecureCredentials struct {
+	credentials.PerRPCCredentials
+}
+
+func (insecureCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// requestIDUnaryInterceptor copies the request id of the context into the outgoing metadata
+func requestIDUnaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(withOutgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+func requestIDStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withOutgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+func withOutgoingRequestID(ctx context.Context) context.Context {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(RequestIDKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, id)
+}
@@ -0,0 +1,193 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataServer records the metadata of the last UserBy call
+type metadataServer struct {
+	testServer
+
+	mu sync.Mutex
+	md metadata.MD
+}
+
+func (s *metadataServer) UserBy(ctx context.Context, req *proto.UserGetter) (*proto.User, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	s.md = md
+	s.mu.Unlock()
+	return s.testServer.UserBy(ctx, req)
+}
+
+func (s *metadataServer) last(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.md.Get(key)
+}
+
+func newMetadataClient(t *testing.T, opts ...Option) (*metadataServer, Client) {
+	t.Helper()
+	srv := &metadataServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr, append([]Option{WithTimeout(2 * time.Second)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { api.Close() })
+	return srv, api
+}
+
+func TestBearerTokenRequiresTLS(t *testing.T) {
+	srv := &metadataServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr, WithStaticToken("service"), WithTimeout(time.Second))
+	if err == nil {
+		defer api.Close()
+		_, err = api.UserByUUID(uuid.Must(uuid.NewV4()))
+	}
+	if err == nil {
+		t.Fatal("a bearer token was sent without TLS")
+	}
+	if got := srv.last(authorizationKey); len(got) != 0 {
+		t.Fatalf("server got authorization %q", got)
+	}
+}
+
+func TestStaticToken(t *testing.T) {
+	srv, api := newMetadataClient(t, WithStaticToken("service"), WithInsecure())
+
+	// the caller token is ignored without WithContextToken
+	ctx := ContextWithToken(context.Background(), "caller")
+	if _, err := api.WithContext(ctx).UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	if got := srv.last(authorizationKey); len(got) != 1 || got[0] != "Bearer service" {
+		t.Fatalf("authorization = %q, want the static token", got)
+	}
+}
+
+func TestContextToken(t *testing.T) {
+	srv, api := newMetadataClient(t, WithContextToken(), WithInsecure())
+
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationKey, "Bearer incoming"))
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{"no context", nil, nil},
+		{"no token", context.Background(), nil},
+		{"context token", ContextWithToken(context.Background(), "caller"), []string{"Bearer caller"}},
+		{"incoming metadata", incoming, []string{"Bearer incoming"}},
+		{"context token first", ContextWithToken(incoming, "caller"), []string{"Bearer caller"}},
+		{"malformed incoming", metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationKey, "Basic abc")), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := api
+			if tt.ctx != nil {
+				client = api.WithContext(tt.ctx)
+			}
+			if _, err := client.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+				t.Fatalf("UserByUUID: %v", err)
+			}
+			if got := srv.last(authorizationKey); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Fatalf("authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithContextCopiesClient(t *testing.T) {
+	srv, api := newMetadataClient(t, WithContextToken(), WithInsecure())
+
+	scoped := api.WithContext(ContextWithToken(context.Background(), "caller"))
+	if scoped == api {
+		t.Fatal("WithContext returned the same client")
+	}
+	if api.(*UsersAPI).ctx != nil {
+		t.Fatal("WithContext changed the context of the client")
+	}
+	if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	if got := srv.last(authorizationKey); len(got) != 0 {
+		t.Fatalf("the original client sent authorization %q", got)
+	}
+	if scoped.(*UsersAPI).ClientConn != api.(*UsersAPI).ClientConn {
+		t.Fatal("WithContext did not share the connection")
+	}
+}
+
+func TestRequestIDSent(t *testing.T) {
+	srv, api := newMetadataClient(t)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	if _, err := api.WithContext(ctx).UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	if got := srv.last(RequestIDKey); len(got) != 1 || got[0] != "req-1" {
+		t.Fatalf("%s = %q, want [req-1]", RequestIDKey, got)
+	}
+
+	if _, err := api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	if got := srv.last(RequestIDKey); len(got) != 0 {
+		t.Fatalf("%s = %q without a request id", RequestIDKey, got)
+	}
+}
+
+func TestRequestIDInterceptors(t *testing.T) {
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "incoming"))
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{"none", context.Background(), nil},
+		{"context", ContextWithRequestID(context.Background(), "req-1"), []string{"req-1"}},
+		{"incoming metadata", incoming, []string{"incoming"}},
+		{"context first", ContextWithRequestID(incoming, "req-1"), []string{"req-1"}},
+		// an id the caller put in the outgoing metadata is kept as the only one
+		{"outgoing metadata", metadata.AppendToOutgoingContext(
+			ContextWithRequestID(context.Background(), "req-1"), RequestIDKey, "outgoing"), []string{"outgoing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(kind string, ctx context.Context) {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				got := md.Get(RequestIDKey)
+				if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+					t.Errorf("%s %s = %q, want %q", kind, RequestIDKey, got, tt.want)
+				}
+			}
+
+			err := requestIDUnaryInterceptor(tt.ctx, "/test/Unary", nil, nil, nil,
+				func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+					check("unary", ctx)
+					return nil
+				})
+			if err != nil {
+				t.Fatalf("unary interceptor: %v", err)
+			}
+			_, err = requestIDStreamInterceptor(tt.ctx, &grpc.StreamDesc{}, nil, "/test/Stream",
+				func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+					check("stream", ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatalf("stream interceptor: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthService is the service name the user service reports its health for
+const healthService = "userapi"
+
+const (
+	healthMinBackoff   = 100 * time.Millisecond
+	healthMaxBackoff   = 10 * time.Second
+	healthPollInterval = 5 * time.Second
+)
+
+// HealthStatus is the serving state of the user service
+type HealthStatus = grpc_health_v1.HealthCheckResponse_ServingStatus
+
+var ErrUnhealthy = errors.New("service is unhealthy")
+
+// WithHealthWatch keeps the serving state up to date in the background
+// with Health/Watch, falling back to polling Health/Check if Watch is not
+// implemented. HealthCheck and Ready then answer from the cached state.
+func WithHealthWatch() Option {
+	return func(api *UsersAPI) {
+		api.health = newHealthWatcher()
+	}
+}
+
+// HealthCheck returns nil when the service is serving. With a health watch
+// the cached state is used, see Probe.
+func (api *UsersAPI) HealthCheck() error {
+	if api.health != nil {
+		if s := api.health.get(); s != grpc_health_v1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("node is %s: %w", s, ErrUnhealthy)
+		}
+		return nil
+	}
+	return api.Probe()
+}
+
+// Probe calls the server even when a health watch is running
+func (api *UsersAPI) Probe() error {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.HealthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: healthService})
+	if err != nil {
+		return fmt.Errorf("healthcheck error: %w", err)
+	}
+	if api.health != nil {
+		api.health.set(resp.Status)
+	}
+
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("node is %s: %w", resp.Status, ErrUnhealthy)
+	}
+	return nil
+}
+
+// Ready reports whether the cached state is serving, always false without WithHealthWatch
+func (api *UsersAPI) Ready() bool {
+	return api.health != nil && api.health.get() == grpc_health_v1.HealthCheckResponse_SERVING
+}
+
+// HealthChanges returns a channel receiving the serving state whenever it
+// changes and a func to stop receiving, which closes the channel. Slow readers
+// only get the latest state. The channel is also closed by Close and is nil
+// without WithHealthWatch.
+func (api *UsersAPI) HealthChanges() (<-chan HealthStatus, func()) {
+	if api.health == nil {
+		return nil, func() {}
+	}
+	return api.health.subscribe()
+}
+
+// healthWatcher caches the serving state reported by the server
+type healthWatcher struct {
+	mu     sync.Mutex
+	status HealthStatus
+	subs   []chan HealthStatus
+	closed bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newHealthWatcher() *healthWatcher {
+	return &healthWatcher{status: grpc_health_v1.HealthCheckResponse_UNKNOWN}
+}
+
+func (w *healthWatcher) get() HealthStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *healthWatcher) set(s HealthStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status == s || w.closed {
+		return
+	}
+	w.status = s
+	for _, ch := range w.subs {
+		// drop a state the reader has not taken yet, it is stale now
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- s
+	}
+}
+
+func (w *healthWatcher) subscribe() (<-chan HealthStatus, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan HealthStatus, 1)
+	if w.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	w.subs = append(w.subs, ch)
+	return ch, func() { w.unsubscribe(ch) }
+}
+
+// unsubscribe closes ch unless stop already did
+func (w *healthWatcher) unsubscribe(ch chan HealthStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, sub := range w.subs {
+		if sub == ch {
+			w.subs = append(w.subs[:i], w.subs[i+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
+
+func (w *healthWatcher) start(client grpc_health_v1.HealthClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go w.run(ctx, client)
+}
+
+func (w *healthWatcher) stop() {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	for _, ch := range w.subs {
+		close(ch)
+	}
+	w.subs = nil
+}
+
+func (w *healthWatcher) run(ctx context.Context, client grpc_health_v1.HealthClient) {
+	defer close(w.done)
+
+	backoff := healthMinBackoff
+	for ctx.Err() == nil {
+		received, err := w.watch(ctx, client)
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			w.poll(ctx, client)
+			return
+		}
+		w.set(grpc_health_v1.HealthCheckResponse_UNKNOWN)
+		if received {
+			backoff = healthMinBackoff
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, healthMaxBackoff)
+	}
+}
+
+// watch follows one Health/Watch stream and reports whether any state arrived
+func (w *healthWatcher) watch(ctx context.Context, client grpc_health_v1.HealthClient) (bool, error) {
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: healthService})
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		w.set(resp.Status)
+	}
+}
+
+// poll is used for servers without Health/Watch
+func (w *healthWatcher) poll(ctx context.Context, client grpc_health_v1.HealthClient) {
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, healthPollInterval)
+		resp, err := client.Check(checkCtx, &grpc_health_v1.HealthCheckRequest{Service: healthService})
+		cancel()
+		if err != nil {
+			w.set(grpc_health_v1.HealthCheckResponse_UNKNOWN)
+		} else {
+			w.set(resp.Status)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newHealthClient(t *testing.T, addr string, opts ...Option) Client {
+	t.Helper()
+	api, err := NewClient(addr, append([]Option{WithTimeout(time.Second)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return api
+}
+
+// nextStatus waits for the next state on changes
+func nextStatus(t *testing.T, changes <-chan HealthStatus) HealthStatus {
+	t.Helper()
+	select {
+	case s, ok := <-changes:
+		if !ok {
+			t.Fatal("health changes closed")
+		}
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("no health change")
+	}
+	return 0
+}
+
+func TestHealthWatchTransitions(t *testing.T) {
+	addr, hs := startTestServer(t, &testServer{})
+	api := newHealthClient(t, addr, WithHealthWatch())
+	defer api.Close()
+
+	changes, unsubscribe := api.HealthChanges()
+	defer unsubscribe()
+	if s := nextStatus(t, changes); s != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("first state = %s, want SERVING", s)
+	}
+	if !api.Ready() || api.HealthCheck() != nil {
+		t.Fatal("not ready while serving")
+	}
+
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if s := nextStatus(t, changes); s != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("state = %s, want NOT_SERVING", s)
+	}
+	// the cached state answers without calling the server
+	if api.Ready() || !errors.Is(api.HealthCheck(), ErrUnhealthy) {
+		t.Fatal("ready while not serving")
+	}
+
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	if s := nextStatus(t, changes); s != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("state = %s, want SERVING", s)
+	}
+}
+
+func TestHealthChangesUnsubscribe(t *testing.T) {
+	w := newHealthWatcher()
+	changes, unsubscribe := w.subscribe()
+	other, _ := w.subscribe()
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-changes; ok {
+		t.Fatal("channel open after unsubscribe")
+	}
+	if len(w.subs) != 1 {
+		t.Fatalf("%d subscribers left, want 1", len(w.subs))
+	}
+
+	w.set(grpc_health_v1.HealthCheckResponse_SERVING)
+	if s := <-other; s != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("other subscriber got %s", s)
+	}
+}
+
+func TestHealthWatchStopsOnClose(t *testing.T) {
+	addr, _ := startTestServer(t, &testServer{})
+	api := newHealthClient(t, addr, WithHealthWatch())
+
+	changes, unsubscribe := api.HealthChanges()
+	nextStatus(t, changes)
+	if err := api.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, ok := <-changes; ok {
+		t.Fatal("health changes open after Close")
+	}
+	unsubscribe()
+
+	late, _ := api.HealthChanges()
+	if _, ok := <-late; ok {
+		t.Fatal("channel subscribed after Close is open")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	addr, hs := startTestServer(t, &testServer{})
+
+	api := newHealthClient(t, addr)
+	defer api.Close()
+	if changes, _ := api.HealthChanges(); changes != nil {
+		t.Fatal("health changes without WithHealthWatch")
+	}
+	if api.Ready() {
+		t.Fatal("ready without WithHealthWatch")
+	}
+	if err := api.HealthCheck(); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if err := api.Probe(); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Probe = %v, want ErrUnhealthy", err)
+	}
+}
+
+// checkOnlyHealth is a health server without Watch
+type checkOnlyHealth struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (checkOnlyHealth) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func TestHealthWatchPollsWithoutWatch(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, checkOnlyHealth{})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	api := newHealthClient(t, lis.Addr().String(), WithHealthWatch())
+	defer api.Close()
+	changes, unsubscribe := api.HealthChanges()
+	defer unsubscribe()
+	if s := nextStatus(t, changes); s != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("polled state = %s, want SERVING", s)
+	}
+}
@@ -0,0 +1,330 @@
+// Package identity verifies ID tokens of external OpenID Connect providers
+// and keeps the links between their subjects and users, for the
+// SignInExternal, LinkIdentity and UnlinkIdentity RPCs.
+package identity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultJWKSMaxAge     = 24 * time.Hour
+	defaultJWKSMinRefresh = time.Minute
+	defaultLeeway         = time.Minute
+	defaultHTTPTimeout    = 10 * time.Second
+	maxJWKSSize           = 1 << 20
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidToken    = errors.New("invalid id token")
+	ErrKeysUnavailable = errors.New("identity provider keys unavailable")
+)
+
+// ProviderConfig configures an external identity provider
+type ProviderConfig struct {
+	// Name identifies the provider in requests and links, e.g. "google"
+	Name string
+	// Issuer must match the iss claim exactly
+	Issuer string
+	// ClientID is the client id registered at the provider, tokens must be issued for it
+	ClientID string
+	// JWKSURL is where the signing keys are fetched from
+	JWKSURL string
+	// JWKS is a fixed key set used instead of JWKSURL
+	JWKS []byte
+}
+
+// Claims are the verified claims of an external ID token
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Nonce         string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	AuthorizedBy  string   `json:"azp"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name"`
+	Nonce         string   `json:"nonce"`
+	IssuedAt      int64    `json:"iat"`
+	Expiry        int64    `json:"exp"`
+}
+
+// audience is the aud claim, a single string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Verifier verifies ID tokens of the configured providers
+type Verifier struct {
+	providers map[string]*provider
+	client    *http.Client
+	leeway    time.Duration
+	now       func() time.Time
+}
+
+type provider struct {
+	cfg  ProviderConfig
+	keys *keySet
+}
+
+// Option configures Verifier
+type Option func(v *Verifier)
+
+// WithHTTPClient sets the client JWKS are fetched with
+func WithHTTPClient(c *http.Client) Option {
+	return func(v *Verifier) {
+		v.client = c
+	}
+}
+
+// WithLeeway sets the clock skew tolerated for exp and iat
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithClock replaces time.Now
+func WithClock(now func() time.Time) Option {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier is
+func NewVerifier(providers []ProviderConfig, opts ...Option) (*Verifier, error) {
+	v := &Verifier{
+		providers: make(map[string]*provider, len(providers)),
+		client:    &http.Client{Timeout: defaultHTTPTimeout},
+		leeway:    defaultLeeway,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	for _, cfg := range providers {
+		if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("provider %q: name, issuer and client id are required", cfg.Name)
+		}
+		if _, ok := v.providers[cfg.Name]; ok {
+			return nil, fmt.Errorf("provider %q: configured twice", cfg.Name)
+		}
+		keys := &keySet{
+			url:        cfg.JWKSURL,
+			client:     v.client,
+			maxAge:     defaultJWKSMaxAge,
+			minRefresh: defaultJWKSMinRefresh,
+			now:        v.now,
+		}
+		switch {
+		case cfg.JWKS != nil:
+			parsed, err := parseKeySet(cfg.JWKS)
+			if err != nil {
+				return nil, fmt.Errorf("provider %q: %w", cfg.Name, err)
+			}
+			keys.keys, keys.static = parsed, true
+		case cfg.JWKSURL == "":
+			return nil, fmt.Errorf("provider %q: a jwks url or key set is required", cfg.Name)
+		}
+		v.providers[cfg.Name] = &provider{cfg: cfg, keys: keys}
+	}
+	return v, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and, when nonce is
+// not empty, the nonce of an ID token issued by the named provider
+func (v *Verifier) Verify(ctx context.Context, providerName, idToken, nonce string) (*Claims, error) {
+	p, ok := v.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, providerName)
+	}
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	key, err := p.keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// the key decides the algorithm, so "none" or HS256 with the public key never pass
+	if header.Alg != key.alg {
+		return nil, fmt.Errorf("%w: unexpected alg %q", ErrInvalidToken, header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !key.verify(parts[0]+"."+parts[1], sig) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var c jwtClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, err
+	}
+	now := v.now()
+	switch {
+	case c.Issuer != p.cfg.Issuer:
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	case !slices.Contains(c.Audience, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	case len(c.Audience) > 1 && c.AuthorizedBy != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: wrong authorized party", ErrInvalidToken)
+	case c.Subject == "":
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	case !now.Before(time.Unix(c.Expiry, 0).Add(v.leeway)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case now.Add(v.leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case nonce != "" && c.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	return &Claims{
+		Issuer:        c.Issuer,
+		Subject:       c.Subject,
+		Audience:      c.Audience,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		Name:          c.Name,
+		Nonce:         c.Nonce,
+		IssuedAt:      time.Unix(c.IssuedAt, 0),
+		ExpiresAt:     time.Unix(c.Expiry, 0),
+	}, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// Linker verifies external ID tokens and resolves or changes their links
+type Linker struct {
+	verifier *Verifier
+	store    Store
+	now      func() time.Time
+}
+
+// NewLinker is
+func NewLinker(verifier *Verifier, store Store) *Linker {
+	return &Linker{verifier: verifier, store: store, now: time.Now}
+}
+
+// SignIn returns the link of the subject of the ID token, the caller issues
+// a session for its UserUUID after checking the account status. The nonce
+// sent in the authentication request is required, so tokens can not be replayed.
+func (l *Linker) SignIn(ctx context.Context, providerName, idToken, nonce string) (*models.ExternalIdentity, error) {
+	if nonce == "" {
+		return nil, models.ErrNonceRequired
+	}
+	claims, err := l.verifier.Verify(ctx, providerName, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return l.store.Lookup(ctx, providerName, claims.Subject)
+}
+
+// Link links the subject of the ID token to the user, the nonce is required as for SignIn
+func (l *Linker) Link(ctx context.Context, userUUID uuid.UUID, providerName, idToken, nonce string) (*models.ExternalIdentity, error) {
+	if nonce == "" {
+		return nil, models.ErrNonceRequired
+	}
+	claims, err := l.verifier.Verify(ctx, providerName, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity := models.ExternalIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		UserUUID: userUUID,
+		Email:    claims.Email,
+		LinkedAt: l.now(),
+	}
+	if err := l.store.Link(ctx, identity); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Unlink is
+func (l *Linker) Unlink(ctx context.Context, userUUID uuid.UUID, providerName string) error {
+	return l.store.Unlink(ctx, userUUID, providerName)
+}
+
+// List is
+func (l *Linker) List(ctx context.Context, userUUID uuid.UUID) ([]*models.ExternalIdentity, error) {
+	return l.store.List(ctx, userUUID)
+}
+
+// Status converts errors of the package and the store to the gRPC status
+// the Go client maps back to models.ErrIdentityNotLinked and ErrIdentityAlreadyLinked
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, models.ErrNonceRequired):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrInvalidToken):
+		code = codes.Unauthenticated
+	case errors.Is(err, ErrKeysUnavailable):
+		code = codes.Unavailable
+	case errors.Is(err, models.ErrIdentityNotLinked):
+		code = codes.NotFound
+	case errors.Is(err, models.ErrIdentityAlreadyLinked):
+		code = codes.AlreadyExists
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,342 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testIssuer   = "https://accounts.example.com"
+	testClientID = "user-service"
+	testNonce    = "n-0S6_WzA2Mj"
+)
+
+// standInProvider serves a JWKS and signs ID tokens with its test keys
+type standInProvider struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	rsaKeys  map[string]*rsa.PrivateKey
+	ecKeys   map[string]*ecdsa.PrivateKey
+	requests int
+}
+
+func newStandInProvider(t *testing.T) *standInProvider {
+	t.Helper()
+	p := &standInProvider{
+		rsaKeys: make(map[string]*rsa.PrivateKey),
+		ecKeys:  make(map[string]*ecdsa.PrivateKey),
+	}
+	p.srv = httptest.NewServer(http.HandlerFunc(p.serveJWKS))
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func (p *standInProvider) addRSAKey(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rsaKeys[kid] = key
+}
+
+func (p *standInProvider) addECKey(t *testing.T, kid string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ecKeys[kid] = key
+}
+
+func (p *standInProvider) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests++
+
+	var set jsonWebKeySet
+	for kid, key := range p.rsaKeys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+			N: b64(key.N.Bytes()),
+			E: b64(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	for kid, key := range p.ecKeys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256",
+			X: b64(key.X.FillBytes(make([]byte, 32))),
+			Y: b64(key.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+func (p *standInProvider) jwksRequests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+// sign returns an ID token signed with the key kid, alg overrides the algorithm of the header
+func (p *standInProvider) sign(t *testing.T, kid, alg string, claims jwtClaims) string {
+	t.Helper()
+	p.mu.Lock()
+	rsaKey, ecKey := p.rsaKeys[kid], p.ecKeys[kid]
+	p.mu.Unlock()
+
+	if alg == "" {
+		alg = "RS256"
+		if ecKey != nil {
+			alg = "ES256"
+		}
+	}
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch {
+	case rsaKey != nil:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+	case ecKey != nil:
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		t.Fatalf("unknown kid %q", kid)
+	}
+	return signed + "." + b64(sig)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// validClaims are claims Verify accepts at now
+func validClaims(now time.Time) jwtClaims {
+	return jwtClaims{
+		Issuer:   testIssuer,
+		Subject:  "subject-1",
+		Audience: audience{testClientID},
+		Email:    "jane@example.com",
+		Nonce:    testNonce,
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(time.Hour).Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T, p *standInProvider, opts ...Option) *Verifier {
+	t.Helper()
+	v, err := NewVerifier([]ProviderConfig{{
+		Name:     "example",
+		Issuer:   testIssuer,
+		ClientID: testClientID,
+		JWKSURL:  p.srv.URL,
+	}}, opts...)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	p := newStandInProvider(t)
+	p.addRSAKey(t, "rsa-1")
+	p.addECKey(t, "ec-1")
+	v := newTestVerifier(t, p)
+	now := time.Now()
+
+	for _, kid := range []string{"rsa-1", "ec-1"} {
+		claims, err := v.Verify(context.Background(), "example", p.sign(t, kid, "", validClaims(now)), testNonce)
+		if err != nil {
+			t.Fatalf("Verify %s token: %v", kid, err)
+		}
+		if claims.Subject != "subject-1" || claims.Email != "jane@example.com" {
+			t.Fatalf("claims = %+v", claims)
+		}
+	}
+	if n := p.jwksRequests(); n != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", n)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	p := newStandInProvider(t)
+	p.addRSAKey(t, "rsa-1")
+	v := newTestVerifier(t, p)
+	now := time.Now()
+
+	tests := map[string]struct {
+		edit  func(*jwtClaims)
+		alg   string
+		nonce string
+	}{
+		"wrong issuer":   {edit: func(c *jwtClaims) { c.Issuer = "https://evil.example.com" }},
+		"wrong audience": {edit: func(c *jwtClaims) { c.Audience = audience{"other"} }},
+		"expired":        {edit: func(c *jwtClaims) { c.Expiry = now.Add(-2 * time.Minute).Unix() }},
+		"future":         {edit: func(c *jwtClaims) { c.IssuedAt = now.Add(time.Hour).Unix() }},
+		"no subject":     {edit: func(c *jwtClaims) { c.Subject = "" }},
+		"wrong nonce":    {nonce: "other"},
+		"alg mismatch":   {alg: "HS256"},
+		"other azp": {edit: func(c *jwtClaims) {
+			c.Audience = audience{testClientID, "other"}
+			c.AuthorizedBy = "other"
+		}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims(now)
+			if tt.edit != nil {
+				tt.edit(&claims)
+			}
+			nonce := testNonce
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			_, err := v.Verify(context.Background(), "example", p.sign(t, "rsa-1", tt.alg, claims), nonce)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	token := p.sign(t, "rsa-1", "", validClaims(now))
+	if _, err := v.Verify(context.Background(), "example", token[:len(token)-4]+"AAAA", testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("tampered signature error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := v.Verify(context.Background(), "unknown", token, testNonce); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("unknown provider error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestVerifyRefetchesRotatedKeys(t *testing.T) {
+	p := newStandInProvider(t)
+	p.addRSAKey(t, "rsa-1")
+	now := time.Now()
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	v := newTestVerifier(t, p, WithClock(clock))
+
+	if _, err := v.Verify(context.Background(), "example", p.sign(t, "rsa-1", "", validClaims(clock())), testNonce); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// a token of a new key is not refetched for within minRefresh
+	p.addRSAKey(t, "rsa-2")
+	rotated := p.sign(t, "rsa-2", "", validClaims(clock()))
+	if _, err := v.Verify(context.Background(), "example", rotated, testNonce); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify error = %v, want ErrInvalidToken before the refresh interval", err)
+	}
+
+	mu.Lock()
+	now = now.Add(defaultJWKSMinRefresh)
+	mu.Unlock()
+	if _, err := v.Verify(context.Background(), "example", rotated, testNonce); err != nil {
+		t.Fatalf("Verify after rotation: %v", err)
+	}
+	if n := p.jwksRequests(); n != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", n)
+	}
+}
+
+func TestLinker(t *testing.T) {
+	p := newStandInProvider(t)
+	p.addRSAKey(t, "rsa-1")
+	l := NewLinker(newTestVerifier(t, p), NewMemoryStore())
+	ctx := context.Background()
+	token := p.sign(t, "rsa-1", "", validClaims(time.Now()))
+	userUUID := uuid.Must(uuid.NewV4())
+
+	if _, err := l.SignIn(ctx, "example", token, testNonce); !errors.Is(err, models.ErrIdentityNotLinked) {
+		t.Fatalf("SignIn before Link error = %v, want ErrIdentityNotLinked", err)
+	}
+	if _, err := l.Link(ctx, userUUID, "example", token, testNonce); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	identity, err := l.SignIn(ctx, "example", token, testNonce)
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	if identity.UserUUID != userUUID || identity.Subject != "subject-1" {
+		t.Fatalf("identity = %+v", identity)
+	}
+	if _, err := l.Link(ctx, uuid.Must(uuid.NewV4()), "example", token, testNonce); !errors.Is(err, models.ErrIdentityAlreadyLinked) {
+		t.Fatalf("Link to another user error = %v, want ErrIdentityAlreadyLinked", err)
+	}
+
+	if err := l.Unlink(ctx, userUUID, "example"); err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if _, err := l.SignIn(ctx, "example", token, testNonce); !errors.Is(err, models.ErrIdentityNotLinked) {
+		t.Fatalf("SignIn after Unlink error = %v, want ErrIdentityNotLinked", err)
+	}
+}
+
+func TestLinkerRequiresNonce(t *testing.T) {
+	p := newStandInProvider(t)
+	p.addRSAKey(t, "rsa-1")
+	l := NewLinker(newTestVerifier(t, p), NewMemoryStore())
+	token := p.sign(t, "rsa-1", "", validClaims(time.Now()))
+
+	if _, err := l.SignIn(context.Background(), "example", token, ""); !errors.Is(err, models.ErrNonceRequired) {
+		t.Fatalf("SignIn error = %v, want ErrNonceRequired", err)
+	}
+	if _, err := l.Link(context.Background(), uuid.Must(uuid.NewV4()), "example", token, ""); !errors.Is(err, models.ErrNonceRequired) {
+		t.Fatalf("Link error = %v, want ErrNonceRequired", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for err, want := range map[error]codes.Code{
+		ErrUnknownProvider:              codes.InvalidArgument,
+		models.ErrNonceRequired:         codes.InvalidArgument,
+		ErrInvalidToken:                 codes.Unauthenticated,
+		ErrKeysUnavailable:              codes.Unavailable,
+		models.ErrIdentityNotLinked:     codes.NotFound,
+		models.ErrIdentityAlreadyLinked: codes.AlreadyExists,
+		errors.New("boom"):              codes.Internal,
+	} {
+		if got := status.Code(Status(err)); got != want {
+			t.Errorf("Status(%v) = %s, want %s", err, got, want)
+		}
+	}
+	if !strings.Contains(Status(ErrInvalidToken).Error(), ErrInvalidToken.Error()) {
+		t.Errorf("Status lost the message")
+	}
+}
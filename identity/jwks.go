@@ -0,0 +1,209 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jsonWebKey is a public key of a JSON Web Key Set, RSA and P-256 keys are supported
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// publicKey is a parsed key together with the only algorithm it verifies
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+func parseKeySet(raw []byte) (map[string]publicKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.parse()
+		if err != nil {
+			// keys of unsupported types are skipped, others of the set may still be used
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) parse() (publicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		if k.Alg != "" && k.Alg != "RS256" {
+			return publicKey{}, fmt.Errorf("unsupported alg %q", k.Alg)
+		}
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return publicKey{}, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return publicKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return publicKey{}, fmt.Errorf("invalid rsa exponent")
+		}
+		return publicKey{alg: "RS256", key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if k.Crv != "P-256" || (k.Alg != "" && k.Alg != "ES256") {
+			return publicKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return publicKey{}, err
+		}
+		raw := make([]byte, 65)
+		raw[0] = 4
+		x.FillBytes(raw[1:33])
+		y.FillBytes(raw[33:])
+		// ecdh rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(raw); err != nil {
+			return publicKey{}, err
+		}
+		return publicKey{alg: "ES256", key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	}
+	return publicKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verify checks the signature of a JWS signing input
+func (k publicKey) verify(signed string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	switch key := k.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	}
+	return false
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// keySet caches the JWKS of one provider. It is refetched when it is older
+// than maxAge or a token has an unknown kid, after a key rotation, but not
+// more often than minRefresh.
+type keySet struct {
+	url        string
+	client     *http.Client
+	maxAge     time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]publicKey
+	fetched   time.Time
+	attempted time.Time
+	static    bool
+}
+
+func (s *keySet) key(ctx context.Context, kid string) (publicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.static {
+		k, ok := s.keys[kid]
+		if !ok {
+			return publicKey{}, fmt.Errorf("%w: unknown kid %q", ErrInvalidToken, kid)
+		}
+		return k, nil
+	}
+
+	now := s.now()
+	k, ok := s.keys[kid]
+	stale := now.Sub(s.fetched) > s.maxAge
+	if (!ok || stale) && now.Sub(s.attempted) >= s.minRefresh {
+		s.attempted = now
+		if err := s.fetch(ctx); err != nil {
+			if ok {
+				// keep verifying with the known key while the provider is unreachable
+				return k, nil
+			}
+			return publicKey{}, err
+		}
+		k, ok = s.keys[kid]
+	}
+	if !ok {
+		if s.keys == nil {
+			return publicKey{}, ErrKeysUnavailable
+		}
+		return publicKey{}, fmt.Errorf("%w: unknown kid %q", ErrInvalidToken, kid)
+	}
+	return k, nil
+}
+
+func (s *keySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("jwks request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: jwks status %d", ErrKeysUnavailable, resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&raw); err != nil {
+		return fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	keys, err := parseKeySet(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	s.keys = keys
+	s.fetched = s.now()
+	return nil
+}
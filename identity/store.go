@@ -0,0 +1,111 @@
+package identity
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+// Store keeps the links between external subjects and users. A subject is
+// linked to one user and a user has at most one identity per provider.
+type Store interface {
+	// Link fails with models.ErrIdentityAlreadyLinked when either side is taken
+	Link(ctx context.Context, identity models.ExternalIdentity) error
+	// Unlink fails with models.ErrIdentityNotLinked
+	Unlink(ctx context.Context, userUUID uuid.UUID, provider string) error
+	// Lookup fails with models.ErrIdentityNotLinked
+	Lookup(ctx context.Context, provider, subject string) (*models.ExternalIdentity, error)
+	// List returns the identities of the user ordered by provider
+	List(ctx context.Context, userUUID uuid.UUID) ([]*models.ExternalIdentity, error)
+}
+
+type subjectKey struct {
+	provider string
+	subject  string
+}
+
+type userKey struct {
+	userUUID uuid.UUID
+	provider string
+}
+
+// MemoryStore is a Store kept in memory
+type MemoryStore struct {
+	mu        sync.RWMutex
+	bySubject map[subjectKey]models.ExternalIdentity
+	byUser    map[userKey]subjectKey
+}
+
+// NewMemoryStore is
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		bySubject: make(map[subjectKey]models.ExternalIdentity),
+		byUser:    make(map[userKey]subjectKey),
+	}
+}
+
+// Link is
+func (s *MemoryStore) Link(_ context.Context, identity models.ExternalIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sk := subjectKey{identity.Provider, identity.Subject}
+	uk := userKey{identity.UserUUID, identity.Provider}
+	if _, ok := s.bySubject[sk]; ok {
+		return models.ErrIdentityAlreadyLinked
+	}
+	if _, ok := s.byUser[uk]; ok {
+		return models.ErrIdentityAlreadyLinked
+	}
+	s.bySubject[sk] = identity
+	s.byUser[uk] = sk
+	return nil
+}
+
+// Unlink is
+func (s *MemoryStore) Unlink(_ context.Context, userUUID uuid.UUID, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uk := userKey{userUUID, provider}
+	sk, ok := s.byUser[uk]
+	if !ok {
+		return models.ErrIdentityNotLinked
+	}
+	delete(s.byUser, uk)
+	delete(s.bySubject, sk)
+	return nil
+}
+
+// Lookup is
+func (s *MemoryStore) Lookup(_ context.Context, provider, subject string) (*models.ExternalIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identity, ok := s.bySubject[subjectKey{provider, subject}]
+	if !ok {
+		return nil, models.ErrIdentityNotLinked
+	}
+	return &identity, nil
+}
+
+// List is
+func (s *MemoryStore) List(_ context.Context, userUUID uuid.UUID) ([]*models.ExternalIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var identities []*models.ExternalIdentity
+	for uk, sk := range s.byUser {
+		if uk.userUUID == userUUID {
+			identity := s.bySubject[sk]
+			identities = append(identities, &identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Provider < identities[j].Provider
+	})
+	return identities, nil
+}
@@ -0,0 +1,14 @@
+// Package bearer parses bearer tokens of Authorization values
+package bearer
+
+import "strings"
+
+// Token extracts the token from an "Authorization: Bearer <token>" value
+func Token(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
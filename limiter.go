@@ -0,0 +1,233 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LimitConfig limits the calls of one method, zero fields are not limited
+type LimitConfig struct {
+	// RequestsPerSecond is the token bucket refill rate
+	RequestsPerSecond float64
+	// Burst is the bucket size, 1 when RequestsPerSecond is set and Burst is not
+	Burst int
+	// MaxInFlight is the max number of concurrent unary calls
+	MaxInFlight int
+}
+
+// WithRateLimit limits the method, e.g. "UpdateUser". An empty method sets
+// the limit of every method without its own. Throttled calls wait until
+// their context is done, streams are only rate limited.
+func WithRateLimit(method string, cfg LimitConfig) Option {
+	return func(api *UsersAPI) {
+		if api.limits == nil {
+			api.limits = make(map[string]LimitConfig)
+		}
+		api.limits[method] = cfg
+	}
+}
+
+// limiter holds the token bucket and semaphore of one method
+type limiter struct {
+	bucket   *rate.Limiter
+	inFlight chan struct{}
+}
+
+type limiters struct {
+	byMethod map[string]*limiter
+	fallback *limiter
+
+	throttled metric.Int64Counter
+	waited    metric.Float64Histogram
+}
+
+func newLimiters(configs map[string]LimitConfig, mp metric.MeterProvider) (*limiters, error) {
+	l := &limiters{byMethod: make(map[string]*limiter)}
+	for method, cfg := range configs {
+		lim := newLimiter(cfg)
+		if method == "" {
+			l.fallback = lim
+			continue
+		}
+		l.byMethod[method] = lim
+	}
+
+	if mp == nil {
+		return l, nil
+	}
+	meter := mp.Meter(instrumentationName)
+	var err error
+	if l.throttled, err = meter.Int64Counter("rpc.client.throttled",
+		metric.WithDescription("Number of user service RPCs delayed or rejected by client limits")); err != nil {
+		return nil, err
+	}
+	if l.waited, err = meter.Float64Histogram("rpc.client.throttle_wait",
+		metric.WithDescription("Time user service RPCs waited for client limits"), metric.WithUnit("ms")); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func newLimiter(cfg LimitConfig) *limiter {
+	lim := &limiter{}
+	if cfg.RequestsPerSecond > 0 {
+		lim.bucket = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), max(cfg.Burst, 1))
+	}
+	if cfg.MaxInFlight > 0 {
+		lim.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return lim
+}
+
+func (l *limiters) get(fullMethod string) *limiter {
+	if lim, ok := l.byMethod[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]; ok {
+		return lim
+	}
+	return l.fallback
+}
+
+func (l *limiters) unaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	lim := l.get(method)
+	if lim == nil || skipsClientLimits(ctx) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	release, err := l.acquire(ctx, method, lim, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (l *limiters) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if lim := l.get(method); lim != nil && !skipsClientLimits(ctx) {
+		release, err := l.acquire(ctx, method, lim, false)
+		if err != nil {
+			return nil, err
+		}
+		release()
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// acquire waits for a token and, with useSlot, for an in-flight slot
+func (l *limiters) acquire(ctx context.Context, method string, lim *limiter, useSlot bool) (func(), error) {
+	start := time.Now()
+	throttled := false
+	reason := ""
+
+	if lim.bucket != nil {
+		waited, err := waitToken(ctx, lim.bucket)
+		if err != nil {
+			l.observe(ctx, method, "rate", start, true)
+			return nil, &limitError{method: method, limit: "rate", err: err}
+		}
+		if waited {
+			throttled, reason = true, "rate"
+		}
+	}
+
+	release := func() {}
+	if useSlot && lim.inFlight != nil {
+		select {
+		case lim.inFlight <- struct{}{}:
+		default:
+			if !throttled {
+				throttled, reason = true, "concurrency"
+			}
+			select {
+			case lim.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				l.observe(ctx, method, "concurrency", start, true)
+				return nil, &limitError{method: method, limit: "concurrency", err: ctx.Err()}
+			}
+		}
+		release = func() { <-lim.inFlight }
+	}
+
+	l.observe(ctx, method, reason, start, throttled)
+	return release, nil
+}
+
+// limitError is returned for a call stopped by a client limit. It carries a
+// gRPC status, so it is handled like the errors of the server, and it is not
+// counted as a failure of the server by the circuit breaker.
+type limitError struct {
+	method string
+	limit  string
+	err    error
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("%s: %s limit: %v", e.method, e.limit, e.err)
+}
+
+func (e *limitError) Unwrap() error {
+	return e.err
+}
+
+// GRPCStatus is ResourceExhausted, or the code of the context error
+func (e *limitError) GRPCStatus() *status.Status {
+	code := codes.ResourceExhausted
+	switch {
+	case errors.Is(e.err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(e.err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.New(code, e.Error())
+}
+
+// waitToken takes a token, waiting for it unless ctx is done first
+// or its deadline comes before the token would be available
+func waitToken(ctx context.Context, bucket *rate.Limiter) (bool, error) {
+	r := bucket.Reserve()
+	if !r.OK() {
+		return false, fmt.Errorf("burst of %d exceeded", bucket.Burst())
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return false, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return true, fmt.Errorf("wait of %s exceeds deadline: %w", delay, context.DeadlineExceeded)
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, nil
+	case <-ctx.Done():
+		r.Cancel()
+		return true, ctx.Err()
+	}
+}
+
+func (l *limiters) observe(ctx context.Context, method, reason string, start time.Time, throttled bool) {
+	if !throttled || l.throttled == nil {
+		return
+	}
+	service, name := splitMethod(method)
+	attrs := metric.WithAttributes(
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", name),
+		attribute.String("reason", reason),
+	)
+	l.throttled.Add(ctx, 1, attrs)
+	l.waited.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs)
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const limitedMethod = "/service.UserService/UserBy"
+
+func newTestLimiters(t *testing.T, configs map[string]LimitConfig) *limiters {
+	t.Helper()
+	l, err := newLimiters(configs, nil)
+	if err != nil {
+		t.Fatalf("newLimiters: %v", err)
+	}
+	return l
+}
+
+// invokeLimited runs invoker through the unary limiter with a timeout
+func invokeLimited(l *limiters, method string, timeout time.Duration, invoker grpc.UnaryInvoker) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return l.unaryInterceptor(ctx, method, nil, nil, nil, invoker)
+}
+
+func okInvoker(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+	return nil
+}
+
+func TestLimiterWaitsForRate(t *testing.T) {
+	l := newTestLimiters(t, map[string]LimitConfig{"UserBy": {RequestsPerSecond: 10}})
+
+	start := time.Now()
+	for range 3 {
+		if err := invokeLimited(l, limitedMethod, time.Second, okInvoker); err != nil {
+			t.Fatalf("call: %v", err)
+		}
+	}
+	// the first call takes the burst, the next two wait 100ms each
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("3 calls at 10/s took %s", elapsed)
+	}
+
+	// other methods have no limit without a fallback
+	start = time.Now()
+	for range 10 {
+		if err := invokeLimited(l, "/service.UserService/ListUsers", time.Second, okInvoker); err != nil {
+			t.Fatalf("call: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("unlimited method waited %s", elapsed)
+	}
+}
+
+func TestLimiterFailsFastWhenDeadlineIsShorterThanWait(t *testing.T) {
+	l := newTestLimiters(t, map[string]LimitConfig{"": {RequestsPerSecond: 1}})
+
+	if err := invokeLimited(l, limitedMethod, time.Second, okInvoker); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	start := time.Now()
+	err := invokeLimited(l, limitedMethod, 200*time.Millisecond, okInvoker)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("call waited %s for a token it could not get in time", elapsed)
+	}
+	var limited *limitError
+	if !errors.As(err, &limited) || limited.limit != "rate" {
+		t.Fatalf("got %v, want a rate limitError", err)
+	}
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		t.Fatalf("code = %s, want DeadlineExceeded", code)
+	}
+}
+
+func TestLimiterBoundsInFlightCalls(t *testing.T) {
+	l := newTestLimiters(t, map[string]LimitConfig{"": {MaxInFlight: 1}})
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	blocking := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		close(entered)
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() { done <- invokeLimited(l, limitedMethod, time.Second, blocking) }()
+	<-entered
+
+	err := invokeLimited(l, limitedMethod, 50*time.Millisecond, okInvoker)
+	var limited *limitError
+	if !errors.As(err, &limited) || limited.limit != "concurrency" {
+		t.Fatalf("got %v, want a concurrency limitError", err)
+	}
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		t.Fatalf("code = %s, want DeadlineExceeded", code)
+	}
+
+	// the slot is given back when the call returns
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("blocking call: %v", err)
+	}
+	if err := invokeLimited(l, limitedMethod, 50*time.Millisecond, okInvoker); err != nil {
+		t.Fatalf("call after the slot was released: %v", err)
+	}
+}
+
+func TestLimitErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{errors.New("burst of 1 exceeded"), codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		err := error(&limitError{method: limitedMethod, limit: "rate", err: tt.err})
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("code of %v = %s, want %s", tt.err, got, tt.want)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v does not wrap %v", err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"iter"
+
+	"github.com/garden-raccoon/user-pkg/models"
+)
+
+// AllUsers walks every page of ListUsers matching the filter.
+// A failed page request is yielded as the error and stops the iteration.
+func AllUsers(api DirectoryAPI, filter models.ListUsersFilter) iter.Seq2[*models.User, error] {
+	return pages(func(cursor string) ([]*models.User, string, error) {
+		page, err := api.ListUsers(filter, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Users, page.NextCursor, nil
+	})
+}
+
+// AllAuditEvents walks every page of ListAuditEvents matching the filter.
+// A failed page request is yielded as the error and stops the iteration.
+func AllAuditEvents(api AuditAPI, filter models.AuditFilter) iter.Seq2[*models.AuditEvent, error] {
+	return pages(func(cursor string) ([]*models.AuditEvent, string, error) {
+		page, err := api.ListAuditEvents(filter, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Events, page.NextCursor, nil
+	})
+}
+
+// pages yields the items of every page returned by fetch, starting at the
+// empty cursor. It stops on an empty or repeated next cursor.
+func pages[T any](fetch func(cursor string) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := ""
+		for {
+			items, next, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if next == "" || next == cursor {
+				return
+			}
+			cursor = next
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	data := map[string]struct {
+		items []int
+		next  string
+	}{
+		"":  {[]int{1, 2}, "a"},
+		"a": {[]int{3}, "b"},
+		"b": {[]int{4}, "b"}, // a repeated cursor ends the walk
+	}
+	var fetched []string
+	fetch := func(cursor string) ([]int, string, error) {
+		fetched = append(fetched, cursor)
+		p := data[cursor]
+		return p.items, p.next, nil
+	}
+
+	var got []int
+	for item, err := range pages(fetch) {
+		if err != nil {
+			t.Fatalf("pages: %v", err)
+		}
+		got = append(got, item)
+	}
+	if len(got) != 4 || got[0] != 1 || got[3] != 4 {
+		t.Fatalf("got %v, want [1 2 3 4]", got)
+	}
+
+	fetched = nil
+	for item := range pages(fetch) {
+		if item == 2 {
+			break
+		}
+	}
+	if len(fetched) != 1 {
+		t.Fatalf("fetched %v after stopping on the first page", fetched)
+	}
+
+	failure := errors.New("unavailable")
+	n := 0
+	for _, err := range pages(func(string) ([]int, string, error) { return nil, "", failure }) {
+		n++
+		if !errors.Is(err, failure) {
+			t.Fatalf("got %v, want the fetch error", err)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("error yielded %d times", n)
+	}
+}
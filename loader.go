@@ -0,0 +1,197 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// DefaultLoaderWait is how long a Loader collects lookups before sending them
+	DefaultLoaderWait = 2 * time.Millisecond
+	// defaultLoaderMaxBatch sends the batch at once when it is full
+	defaultLoaderMaxBatch = batchChunkSize
+)
+
+// Loader coalesces concurrent UserByUUID lookups into UsersByUUIDs requests.
+// Every uuid is requested once per Loader, so create one Loader per inbound
+// request and pass it down with WithLoader. UserByUUID of a client given
+// that context with WithContext then goes through the Loader.
+type Loader struct {
+	api      DirectoryAPI
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]*loaderResult
+	batch *loaderBatch
+}
+
+type loaderResult struct {
+	done chan struct{}
+	// waiters is the number of Loads waiting, guarded by Loader.mu
+	waiters int
+	user    *models.User
+	err     error
+}
+
+type loaderBatch struct {
+	ids     []uuid.UUID
+	results []*loaderResult
+	timer   *time.Timer
+}
+
+// NewLoader creates a Loader waiting wait for more lookups before each batch,
+// zero wait means DefaultLoaderWait
+func NewLoader(api DirectoryAPI, wait time.Duration) *Loader {
+	if wait <= 0 {
+		wait = DefaultLoaderWait
+	}
+	return &Loader{
+		api:      api,
+		wait:     wait,
+		maxBatch: defaultLoaderMaxBatch,
+		cache:    make(map[uuid.UUID]*loaderResult),
+	}
+}
+
+// Load returns the user, models.ErrUserNotFound is wrapped for unknown uuids.
+// A uuid whose every Load is cancelled before its batch is sent is not requested.
+func (l *Loader) Load(ctx context.Context, userUUID uuid.UUID) (*models.User, error) {
+	l.mu.Lock()
+	res, ok := l.cache[userUUID]
+	if !ok {
+		res = &loaderResult{done: make(chan struct{})}
+		l.cache[userUUID] = res
+		l.enqueue(userUUID, res)
+	}
+	res.waiters++
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.user, res.err
+	case <-ctx.Done():
+		l.cancel(userUUID, res)
+		return nil, ctx.Err()
+	}
+}
+
+// cancel drops a waiter of res, the last one takes the uuid out of the
+// pending batch and the cache
+func (l *Loader) cancel(userUUID uuid.UUID, res *loaderResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	res.waiters--
+	if res.waiters > 0 {
+		return
+	}
+	select {
+	case <-res.done:
+		return
+	default:
+	}
+	if l.cache[userUUID] == res {
+		delete(l.cache, userUUID)
+	}
+	b := l.batch
+	if b == nil {
+		return
+	}
+	for i, r := range b.results {
+		if r == res {
+			b.ids = append(b.ids[:i], b.ids[i+1:]...)
+			b.results = append(b.results[:i], b.results[i+1:]...)
+			break
+		}
+	}
+	if len(b.ids) == 0 && b.timer.Stop() {
+		l.batch = nil
+	}
+}
+
+// enqueue must be called with l.mu held
+func (l *Loader) enqueue(userUUID uuid.UUID, res *loaderResult) {
+	if l.batch == nil {
+		b := &loaderBatch{}
+		b.timer = time.AfterFunc(l.wait, func() { l.dispatch(b) })
+		l.batch = b
+	}
+	l.batch.ids = append(l.batch.ids, userUUID)
+	l.batch.results = append(l.batch.results, res)
+	if len(l.batch.ids) >= l.maxBatch {
+		b := l.batch
+		l.batch = nil
+		if b.timer.Stop() {
+			go l.dispatch(b)
+		}
+	}
+}
+
+func (l *Loader) dispatch(b *loaderBatch) {
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	var (
+		ids     []uuid.UUID
+		results []*loaderResult
+	)
+	for i, res := range b.results {
+		if res.waiters > 0 {
+			ids = append(ids, b.ids[i])
+			results = append(results, res)
+		}
+	}
+	l.mu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+	found, err := l.api.UsersByUUIDs(ids)
+
+	users := make(map[uuid.UUID]*models.User)
+	if found != nil {
+		for _, u := range found.Users {
+			users[u.UserUUID] = u
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, id := range ids {
+		res := results[i]
+		switch {
+		case err != nil:
+			res.err = fmt.Errorf("load user %s: %w", id, err)
+			// failures are not cached, the next Load retries
+			if l.cache[id] == res {
+				delete(l.cache, id)
+			}
+		case users[id] == nil:
+			res.err = fmt.Errorf("load user %s: %w", id, models.ErrUserNotFound)
+		default:
+			res.user = users[id]
+		}
+		close(res.done)
+	}
+}
+
+type loaderKey struct{}
+
+// WithLoader returns a copy of ctx carrying the loader, used by
+// UsersAPI.UserByUUID when the client is given ctx with WithContext
+func WithLoader(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// LoaderFromContext returns the loader set by WithLoader or nil
+func LoaderFromContext(ctx context.Context) *Loader {
+	l, _ := ctx.Value(loaderKey{}).(*Loader)
+	return l
+}
@@ -0,0 +1,266 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+	"github.com/gofrs/uuid"
+)
+
+// fakeDirectory answers UsersByUUIDs and records every batch it was asked for
+type fakeDirectory struct {
+	DirectoryAPI
+
+	mu      sync.Mutex
+	batches [][]uuid.UUID
+	missing map[uuid.UUID]bool
+	err     error
+}
+
+func (d *fakeDirectory) UsersByUUIDs(ids []uuid.UUID) (*models.UsersBatch, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.batches = append(d.batches, append([]uuid.UUID(nil), ids...))
+	if d.err != nil {
+		return nil, d.err
+	}
+	batch := &models.UsersBatch{}
+	for _, id := range ids {
+		if d.missing[id] {
+			batch.Missing = append(batch.Missing, id)
+			continue
+		}
+		batch.Users = append(batch.Users, &models.User{UserUUID: id})
+	}
+	return batch, nil
+}
+
+func (d *fakeDirectory) requested() [][]uuid.UUID {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]uuid.UUID(nil), d.batches...)
+}
+
+func newUUIDs(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV4())
+	}
+	return ids
+}
+
+func TestLoaderDeduplicatesConcurrentLoads(t *testing.T) {
+	dir := &fakeDirectory{}
+	l := NewLoader(dir, 20*time.Millisecond)
+	ids := newUUIDs(3)
+
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func(id uuid.UUID) {
+			defer wg.Done()
+			u, err := l.Load(context.Background(), id)
+			if err != nil {
+				t.Errorf("Load: %v", err)
+				return
+			}
+			if u.UserUUID != id {
+				t.Errorf("Load(%s) returned %s", id, u.UserUUID)
+			}
+		}(ids[i%len(ids)])
+	}
+	wg.Wait()
+
+	batches := dir.requested()
+	if len(batches) != 1 || len(batches[0]) != len(ids) {
+		t.Fatalf("requested %v, want one batch of %d uuids", batches, len(ids))
+	}
+
+	// loaded users are kept for the life of the loader
+	if _, err := l.Load(context.Background(), ids[0]); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if n := len(dir.requested()); n != 1 {
+		t.Fatalf("%d requests after a cached Load, want 1", n)
+	}
+}
+
+func TestLoaderFlushesFullBatch(t *testing.T) {
+	dir := &fakeDirectory{}
+	l := NewLoader(dir, time.Hour)
+	l.maxBatch = 3
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, id := range newUUIDs(3) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.Load(ctx, id); err != nil {
+				t.Errorf("Load: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if batches := dir.requested(); len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("requested %v, want one batch of 3 uuids", batches)
+	}
+}
+
+func TestLoaderFlushesAfterWait(t *testing.T) {
+	dir := &fakeDirectory{}
+	l := NewLoader(dir, 10*time.Millisecond)
+	id := uuid.Must(uuid.NewV4())
+	dir.missing = map[uuid.UUID]bool{id: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := l.Load(ctx, id); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("Load error = %v, want ErrUserNotFound", err)
+	}
+	if batches := dir.requested(); len(batches) != 1 {
+		t.Fatalf("requested %v, want one batch", batches)
+	}
+}
+
+func TestLoaderErrorReachesEveryWaiter(t *testing.T) {
+	errDown := errors.New("service down")
+	dir := &fakeDirectory{err: errDown}
+	l := NewLoader(dir, 10*time.Millisecond)
+	ids := newUUIDs(5)
+
+	var wg sync.WaitGroup
+	for _, id := range append(ids, ids...) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.Load(context.Background(), id); !errors.Is(err, errDown) {
+				t.Errorf("Load error = %v, want %v", err, errDown)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// failures are not cached
+	dir.mu.Lock()
+	dir.err = nil
+	dir.mu.Unlock()
+	if _, err := l.Load(context.Background(), ids[0]); err != nil {
+		t.Fatalf("Load after failure: %v", err)
+	}
+}
+
+func TestLoaderCancelledLoad(t *testing.T) {
+	dir := &fakeDirectory{}
+	l := NewLoader(dir, 20*time.Millisecond)
+	cancelled, kept := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := l.Load(ctx, cancelled)
+		errs <- err
+	}()
+	go func() {
+		_, err := l.Load(context.Background(), kept)
+		errs <- err
+	}()
+	// the shared uuid keeps a waiter, so it is still requested
+	shared := make(chan error, 1)
+	sharedCtx, cancelShared := context.WithCancel(context.Background())
+	go func() {
+		_, err := l.Load(sharedCtx, kept)
+		shared <- err
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	cancelShared()
+	for range 2 {
+		if err := <-errs; err != nil && !errors.Is(err, context.Canceled) {
+			t.Fatalf("Load: %v", err)
+		}
+	}
+	if err := <-shared; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Load error = %v, want context.Canceled", err)
+	}
+
+	batches := dir.requested()
+	if len(batches) != 1 || len(batches[0]) != 1 || batches[0][0] != kept {
+		t.Fatalf("requested %v, want only %s", batches, kept)
+	}
+}
+
+func TestLoaderAllLoadsCancelled(t *testing.T) {
+	dir := &fakeDirectory{}
+	l := NewLoader(dir, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Load(ctx, uuid.Must(uuid.NewV4())); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Load error = %v, want context.Canceled", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if batches := dir.requested(); len(batches) != 0 {
+		t.Fatalf("requested %v for cancelled loads", batches)
+	}
+}
+
+// TestUserByUUIDUsesContextLoader checks lookups of a client given a
+// context with a loader are batched into one UsersByUUIDs
+func TestUserByUUIDUsesContextLoader(t *testing.T) {
+	srv := &testServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr, WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	ids := newUUIDs(3)
+	var lookups atomic.Int64
+	srv.userBy = func(_ context.Context, req *proto.UserGetter) (*proto.User, error) {
+		lookups.Add(1)
+		return &proto.User{UserUuid: req.GetUserUuid()}, nil
+	}
+
+	scoped := api.WithContext(WithLoader(context.Background(), NewLoader(api, 50*time.Millisecond)))
+	var wg sync.WaitGroup
+	for _, id := range append(ids, ids[0]) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := scoped.UserByUUID(id)
+			if err != nil {
+				t.Errorf("UserByUUID: %v", err)
+				return
+			}
+			if u.UserUUID != id {
+				t.Errorf("UserByUUID(%s) = %s", id, u.UserUUID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := lookups.Load(); n != 0 {
+		t.Fatalf("%d UserBy calls, want the lookups batched", n)
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Fatalf("%d calls to the server, want 1 UsersByUUIDs", n)
+	}
+
+	if _, err := api.UserByUUID(ids[0]); err != nil {
+		t.Fatalf("UserByUUID without a loader: %v", err)
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Fatalf("%d UserBy calls without a loader, want 1", n)
+	}
+}
@@ -0,0 +1,205 @@
+// Package metrics exposes Prometheus metrics of the user service server.
+// Everything is registered on the registry owned by Metrics, never on the
+// prometheus default registry, so several servers can live in one process.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPath is where Serve exposes the metrics when no path is given
+	DefaultPath = "/metrics"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Metrics holds the user service collectors
+type Metrics struct {
+	registry *prometheus.Registry
+
+	rpcRequests  *prometheus.CounterVec
+	rpcDuration  *prometheus.HistogramVec
+	rpcInFlight  *prometheus.GaugeVec
+	signIns      *prometheus.CounterVec
+	sessions     prometheus.Gauge
+	lockouts     prometheus.Counter
+	tokenFailure *prometheus.CounterVec
+}
+
+// New creates the collectors under namespace on a new registry,
+// Go runtime and process collectors are registered as well
+func New(namespace string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		rpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "gRPC requests handled, by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC request duration, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		rpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_in_flight",
+			Help:      "gRPC requests being handled, by method.",
+		}, []string{"method"}),
+		signIns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sign_ins_total",
+			Help:      "Sign in attempts, by result.",
+		}, []string{"result"}),
+		sessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_sessions",
+			Help:      "Sessions that are currently valid.",
+		}),
+		lockouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lockouts_total",
+			Help:      "Accounts locked after too many failed sign ins.",
+		}),
+		tokenFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_verification_failures_total",
+			Help:      "Tokens rejected by CheckAuth, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		m.rpcRequests, m.rpcDuration, m.rpcInFlight, m.signIns, m.sessions, m.lockouts, m.tokenFailure,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Registry is the registry the collectors are registered on
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// SignIn counts a sign in attempt
+func (m *Metrics) SignIn(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	m.signIns.WithLabelValues(result).Inc()
+}
+
+// SetActiveSessions sets the number of valid sessions
+func (m *Metrics) SetActiveSessions(n int) {
+	m.sessions.Set(float64(n))
+}
+
+// SessionStarted is
+func (m *Metrics) SessionStarted() {
+	m.sessions.Inc()
+}
+
+// SessionEnded is
+func (m *Metrics) SessionEnded() {
+	m.sessions.Dec()
+}
+
+// Lockout counts a locked account
+func (m *Metrics) Lockout() {
+	m.lockouts.Inc()
+}
+
+// TokenVerificationFailed counts a rejected token, reason is e.g. "expired" or "signature"
+func (m *Metrics) TokenVerificationFailed(reason string) {
+	m.tokenFailure.WithLabelValues(reason).Inc()
+}
+
+// UnaryServerInterceptor records rate, errors, duration and in-flight unary RPCs
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		method := methodName(info.FullMethod)
+		inFlight := m.rpcInFlight.WithLabelValues(method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observe(method, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records rate, errors, duration and in-flight streaming RPCs
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		method := methodName(info.FullMethod)
+		inFlight := m.rpcInFlight.WithLabelValues(method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observe(method, start, err)
+		return err
+	}
+}
+
+// methodName is the method of "/package.Service/Method"
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+func (m *Metrics) observe(method string, start time.Time, err error) {
+	m.rpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve exposes the metrics on addr at path until ctx is done,
+// empty path means DefaultPath
+func (m *Metrics) Serve(ctx context.Context, addr, path string) error {
+	if path == "" {
+		path = DefaultPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, m.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("serve metrics: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("shutdown metrics server: %w", err)
+		}
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("serve metrics: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const method = "/user.UserService/UserBy"
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New("users")
+	intercept := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	var inFlight float64
+	_, err := intercept(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		inFlight = testutil.ToFloat64(m.rpcInFlight.WithLabelValues("UserBy"))
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	if inFlight != 1 {
+		t.Fatalf("in flight during the call = %v, want 1", inFlight)
+	}
+
+	notFound := status.Error(codes.NotFound, "no user")
+	if _, err := intercept(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, notFound
+	}); !errors.Is(err, notFound) {
+		t.Fatalf("interceptor error = %v, want the handler error", err)
+	}
+
+	if n := testutil.ToFloat64(m.rpcRequests.WithLabelValues("UserBy", "OK")); n != 1 {
+		t.Fatalf("OK requests = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(m.rpcRequests.WithLabelValues("UserBy", "NotFound")); n != 1 {
+		t.Fatalf("NotFound requests = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(m.rpcInFlight.WithLabelValues("UserBy")); n != 0 {
+		t.Fatalf("in flight after the calls = %v, want 0", n)
+	}
+	if n := testutil.CollectAndCount(m.rpcDuration, "users_grpc_request_duration_seconds"); n != 1 {
+		t.Fatalf("%d duration series, want 1 for the method", n)
+	}
+	checkObservations(t, m.rpcDuration, 2)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	m := New("users")
+	intercept := m.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/user.UserService/WatchUsers", IsServerStream: true}
+
+	var inFlight float64
+	err := intercept(nil, nil, info, func(any, grpc.ServerStream) error {
+		inFlight = testutil.ToFloat64(m.rpcInFlight.WithLabelValues("WatchUsers"))
+		return status.Error(codes.Unavailable, "shutting down")
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("interceptor error = %v, want the handler error", err)
+	}
+	if inFlight != 1 {
+		t.Fatalf("in flight during the stream = %v, want 1", inFlight)
+	}
+	if n := testutil.ToFloat64(m.rpcRequests.WithLabelValues("WatchUsers", "Unavailable")); n != 1 {
+		t.Fatalf("Unavailable streams = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(m.rpcInFlight.WithLabelValues("WatchUsers")); n != 0 {
+		t.Fatalf("in flight after the stream = %v, want 0", n)
+	}
+	checkObservations(t, m.rpcDuration, 1)
+}
+
+// checkObservations checks the single series of hv has n observations
+func checkObservations(t *testing.T, hv *prometheus.HistogramVec, n uint64) {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 1)
+	hv.Collect(ch)
+	close(ch)
+	for metric := range ch {
+		var pb dto.Metric
+		if err := metric.Write(&pb); err != nil {
+			t.Fatalf("write metric: %v", err)
+		}
+		if got := pb.GetHistogram().GetSampleCount(); got != n {
+			t.Fatalf("%d observations, want %d", got, n)
+		}
+	}
+}
+
+func TestServerCounters(t *testing.T) {
+	m := New("users")
+	m.SignIn(true)
+	m.SignIn(false)
+	m.SignIn(false)
+	m.Lockout()
+	m.TokenVerificationFailed("expired")
+	m.SetActiveSessions(3)
+	m.SessionStarted()
+	m.SessionEnded()
+	m.SessionEnded()
+
+	for name, tt := range map[string]struct {
+		got, want float64
+	}{
+		"successful sign ins": {testutil.ToFloat64(m.signIns.WithLabelValues("success")), 1},
+		"failed sign ins":     {testutil.ToFloat64(m.signIns.WithLabelValues("failure")), 2},
+		"lockouts":            {testutil.ToFloat64(m.lockouts), 1},
+		"expired tokens":      {testutil.ToFloat64(m.tokenFailure.WithLabelValues("expired")), 1},
+		"sessions":            {testutil.ToFloat64(m.sessions), 2},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestServeOwnRegistry checks Serve exposes the collectors of m and
+// nothing registered on the prometheus default registry
+func TestServeOwnRegistry(t *testing.T) {
+	outsider := prometheus.NewCounter(prometheus.CounterOpts{Name: "metrics_test_outsider_total", Help: "Not ours."})
+	prometheus.MustRegister(outsider)
+	defer prometheus.Unregister(outsider)
+
+	m := New("users")
+	m.Lockout()
+	// two instances in one process do not conflict
+	New("users").Lockout()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- m.Serve(ctx, addr, "")
+	}()
+
+	var body string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://" + addr + DefaultPath)
+		if err == nil {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = string(b)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s: %v", DefaultPath, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, want := range []string{"users_lockouts_total 1", "go_goroutines"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics have no %q", want)
+		}
+	}
+	if strings.Contains(body, "metrics_test_outsider_total") {
+		t.Error("metrics of the default registry are exposed")
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after ctx was done")
+	}
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AuditEventType is
+type AuditEventType int
+
+const (
+	AuditSignUp       = AuditEventType(proto.AuditEventType_AUDIT_EVENT_TYPE_SIGN_UP)
+	AuditSignIn       = AuditEventType(proto.AuditEventType_AUDIT_EVENT_TYPE_SIGN_IN)
+	AuditUserUpdated  = AuditEventType(proto.AuditEventType_AUDIT_EVENT_TYPE_USER_UPDATED)
+	AuditTokenRevoked = AuditEventType(proto.AuditEventType_AUDIT_EVENT_TYPE_TOKEN_REVOKED)
+	AuditAdminAction  = AuditEventType(proto.AuditEventType_AUDIT_EVENT_TYPE_ADMIN_ACTION)
+)
+
+func (t AuditEventType) String() string {
+	switch t {
+	case AuditSignUp:
+		return "sign_up"
+	case AuditSignIn:
+		return "sign_in"
+	case AuditUserUpdated:
+		return "user_updated"
+	case AuditTokenRevoked:
+		return "token_revoked"
+	case AuditAdminAction:
+		return "admin_action"
+	}
+	return fmt.Sprintf("AuditEventType(%d)", int(t))
+}
+
+// AuditEvent answers who did what to which user and when
+type AuditEvent struct {
+	EventUUID  uuid.UUID
+	Type       AuditEventType
+	UserUUID   uuid.UUID
+	ActorUUID  uuid.UUID
+	OccurredAt time.Time
+	Success    bool
+	Changes    []FieldChange
+	Detail     string
+}
+
+// FieldChange is the before and after value of one changed field
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// AuditFilter selects audit events, zero fields match everything
+type AuditFilter struct {
+	UserUUID uuid.UUID
+	Types    []AuditEventType
+	// PageSize is the server default when zero
+	PageSize int
+}
+
+// AuditPage is one page of ListAuditEvents, NextCursor is empty on the last page
+type AuditPage struct {
+	Events     []*AuditEvent
+	NextCursor string
+}
+
+// Matches reports whether the event passes the filter
+func (f AuditFilter) Matches(e *AuditEvent) bool {
+	if f.UserUUID != uuid.Nil && e.UserUUID != f.UserUUID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the profile fields that differ between before and after
+func Diff(before, after User) []FieldChange {
+	var changes []FieldChange
+	add := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	add("email", before.Email, after.Email)
+	add("username", before.Username, after.Username)
+	add("first_name", before.FirstName, after.FirstName)
+	add("last_name", before.LastName, after.LastName)
+	add("avatar", before.Avatar, after.Avatar)
+	add("user_type", fmt.Sprint(before.UserType), fmt.Sprint(after.UserType))
+	add("status", before.Status.String(), after.Status.String())
+	return changes
+}
+
+// AuditEventFromProto is
+func AuditEventFromProto(pb *proto.AuditEvent) *AuditEvent {
+	e := &AuditEvent{
+		EventUUID: uuid.FromBytesOrNil(pb.EventUuid),
+		Type:      AuditEventType(pb.Type),
+		UserUUID:  uuid.FromBytesOrNil(pb.UserUuid),
+		ActorUUID: uuid.FromBytesOrNil(pb.ActorUuid),
+		Success:   pb.Success,
+		Detail:    pb.Detail,
+	}
+	if pb.OccurredAt != nil {
+		e.OccurredAt = pb.OccurredAt.AsTime()
+	}
+	for _, c := range pb.Changes {
+		e.Changes = append(e.Changes, FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return e
+}
+
+// Proto is
+func (e AuditEvent) Proto() *proto.AuditEvent {
+	pb := &proto.AuditEvent{
+		EventUuid: e.EventUUID.Bytes(),
+		Type:      proto.AuditEventType(e.Type),
+		UserUuid:  e.UserUUID.Bytes(),
+		ActorUuid: e.ActorUUID.Bytes(),
+		Success:   e.Success,
+		Detail:    e.Detail,
+	}
+	if !e.OccurredAt.IsZero() {
+		pb.OccurredAt = timestamppb.New(e.OccurredAt)
+	}
+	for _, c := range e.Changes {
+		pb.Changes = append(pb.Changes, &proto.FieldChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return pb
+}
+
+// Proto is
+func (f AuditFilter) Proto(cursor string) *proto.ListAuditEventsRequest {
+	req := &proto.ListAuditEventsRequest{
+		PageSize: int32(f.PageSize),
+		Cursor:   cursor,
+	}
+	if f.UserUUID != uuid.Nil {
+		req.UserUuid = f.UserUUID.Bytes()
+	}
+	for _, t := range f.Types {
+		req.Types = append(req.Types, proto.AuditEventType(t))
+	}
+	return req
+}
+
+// AuditFilterFromProto is
+func AuditFilterFromProto(pb *proto.ListAuditEventsRequest) *AuditFilter {
+	f := &AuditFilter{
+		UserUUID: uuid.FromBytesOrNil(pb.UserUuid),
+		PageSize: int(pb.PageSize),
+	}
+	for _, t := range pb.Types {
+		f.Types = append(f.Types, AuditEventType(t))
+	}
+	return f
+}
+
+// AuditPageFromProto is
+func AuditPageFromProto(pb *proto.ListAuditEventsResponse) *AuditPage {
+	page := &AuditPage{
+		Events:     make([]*AuditEvent, 0, len(pb.Events)),
+		NextCursor: pb.NextCursor,
+	}
+	for _, e := range pb.Events {
+		page.Events = append(page.Events, AuditEventFromProto(e))
+	}
+	return page
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+)
+
+// UsersBatch is the result of a batch lookup
+type UsersBatch struct {
+	Users   []*User
+	Missing []uuid.UUID
+}
+
+// UsersByUUIDsRequest is
+func UsersByUUIDsRequest(userUUIDs []uuid.UUID) *proto.UsersByUUIDsRequest {
+	req := &proto.UsersByUUIDsRequest{UserUuids: make([][]byte, 0, len(userUUIDs))}
+	for _, id := range userUUIDs {
+		req.UserUuids = append(req.UserUuids, id.Bytes())
+	}
+	return req
+}
+
+// UsersBatchFromProto is
+func UsersBatchFromProto(pb *proto.UsersByUUIDsResponse) *UsersBatch {
+	batch := &UsersBatch{Users: make([]*User, 0, len(pb.Users))}
+	for _, u := range pb.Users {
+		batch.Users = append(batch.Users, UserFromProto(u))
+	}
+	for _, id := range pb.MissingUuids {
+		batch.Missing = append(batch.Missing, uuid.FromBytesOrNil(id))
+	}
+	return batch
+}
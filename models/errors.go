@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUserNotFound is returned for lookups of users that do not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// FieldViolation describes a single invalid field of a request
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError lists every field violation found in a request.
+// It is sent over gRPC as InvalidArgument with BadRequest details,
+// so the client gets the same violations the server found.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// Add appends a violation for field
+func (e *ValidationError) Add(field, description string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Description: description})
+}
+
+// Merge appends all violations of other
+func (e *ValidationError) Merge(other *ValidationError) {
+	if other != nil {
+		e.Violations = append(e.Violations, other.Violations...)
+	}
+}
+
+// Err returns nil when there are no violations
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// GRPCStatus is used by grpc status.FromError and by servers returning the error as is
+func (e *ValidationError) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, e.Error())
+	br := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	detailed, err := st.WithDetails(br)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// ValidationErrorFromError extracts field violations from a local
+// ValidationError or from a gRPC InvalidArgument status with BadRequest details
+func ValidationErrorFromError(err error) (*ValidationError, bool) {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve, true
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+	ve = &ValidationError{}
+	for _, d := range st.Details() {
+		br, ok := d.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, fv := range br.FieldViolations {
+			ve.Add(fv.Field, fv.Description)
+		}
+	}
+	if len(ve.Violations) == 0 {
+		return nil, false
+	}
+	return ve, true
+}
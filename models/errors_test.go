@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidationErrorGRPCRoundTrip(t *testing.T) {
+	sent := &ValidationError{}
+	sent.Add("email", "must be a valid email address")
+	sent.Add("password", "is too common")
+
+	// what the server returns is marshalled, sent and read back by the client
+	b, err := proto.Marshal(status.Convert(fmt.Errorf("createUser: %w", sent)).Proto())
+	if err != nil {
+		t.Fatalf("marshal status: %v", err)
+	}
+	var pb spb.Status
+	if err := proto.Unmarshal(b, &pb); err != nil {
+		t.Fatalf("unmarshal status: %v", err)
+	}
+	received := status.FromProto(&pb).Err()
+
+	if code := status.Code(received); code != codes.InvalidArgument {
+		t.Fatalf("code = %s, want InvalidArgument", code)
+	}
+	got, ok := ValidationErrorFromError(received)
+	if !ok {
+		t.Fatalf("no violations in %v", received)
+	}
+	if !reflect.DeepEqual(got.Violations, sent.Violations) {
+		t.Fatalf("violations = %+v, want %+v", got.Violations, sent.Violations)
+	}
+}
+
+func TestValidationErrorFromError(t *testing.T) {
+	local := &ValidationError{}
+	local.Add("email", "is required")
+	if got, ok := ValidationErrorFromError(fmt.Errorf("wrapped: %w", local)); !ok || got != local {
+		t.Fatalf("ValidationErrorFromError of a local error = %v, %t", got, ok)
+	}
+
+	for _, err := range []error{
+		errors.New("plain"),
+		status.Error(codes.InvalidArgument, "no details"),
+		status.Error(codes.NotFound, "not found"),
+	} {
+		if got, ok := ValidationErrorFromError(err); ok {
+			t.Fatalf("ValidationErrorFromError(%v) = %v", err, got)
+		}
+	}
+
+	if err := (&ValidationError{}).Err(); err != nil {
+		t.Fatalf("Err of no violations = %v", err)
+	}
+	var nilErr *ValidationError
+	if err := nilErr.Err(); err != nil {
+		t.Fatalf("Err of nil = %v", err)
+	}
+}
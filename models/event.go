@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserEventType is
+type UserEventType int
+
+const (
+	UserCreated = UserEventType(proto.UserEventType_USER_EVENT_TYPE_CREATED)
+	UserUpdated = UserEventType(proto.UserEventType_USER_EVENT_TYPE_UPDATED)
+	UserDeleted = UserEventType(proto.UserEventType_USER_EVENT_TYPE_DELETED)
+)
+
+func (t UserEventType) String() string {
+	switch t {
+	case UserCreated:
+		return "created"
+	case UserUpdated:
+		return "updated"
+	case UserDeleted:
+		return "deleted"
+	}
+	return fmt.Sprintf("UserEventType(%d)", int(t))
+}
+
+// UserEvent is a change of a user, Sequence is the resume cursor
+type UserEvent struct {
+	Sequence   uint64
+	Type       UserEventType
+	User       *User
+	OccurredAt time.Time
+}
+
+// UserEventFromProto is
+func UserEventFromProto(pb *proto.UserEvent) *UserEvent {
+	e := &UserEvent{
+		Sequence: pb.Sequence,
+		Type:     UserEventType(pb.Type),
+	}
+	if pb.User != nil {
+		e.User = UserFromProto(pb.User)
+	}
+	if pb.OccurredAt != nil {
+		e.OccurredAt = pb.OccurredAt.AsTime()
+	}
+	return e
+}
+
+// Proto is
+func (e UserEvent) Proto() *proto.UserEvent {
+	pb := &proto.UserEvent{
+		Sequence: e.Sequence,
+		Type:     proto.UserEventType(e.Type),
+	}
+	if e.User != nil {
+		pb.User = e.User.Proto()
+	}
+	if !e.OccurredAt.IsZero() {
+		pb.OccurredAt = timestamppb.New(e.OccurredAt)
+	}
+	return pb
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserDataExport is the answer to a data subject access request
+type UserDataExport struct {
+	UserUUID   uuid.UUID
+	ExportedAt time.Time
+	// Document is the JSON encoded UserData
+	Document json.RawMessage
+}
+
+// UserData is the layout of UserDataExport.Document
+type UserData struct {
+	Profile  ProfileData       `json:"profile"`
+	Sessions []json.RawMessage `json:"sessions"`
+	Audit    []json.RawMessage `json:"audit"`
+}
+
+// ProfileData is the user in an export, its JSON names are part of the export format
+type ProfileData struct {
+	UserUUID  uuid.UUID  `json:"user_uuid"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	UserType  int        `json:"user_type"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Avatar    string     `json:"avatar"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+// ProfileDataFromUser is
+func ProfileDataFromUser(u User) ProfileData {
+	p := ProfileData{
+		UserUUID:  u.UserUUID,
+		Username:  u.Username,
+		Email:     u.Email,
+		UserType:  u.UserType,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Avatar:    u.Avatar,
+		Status:    u.Status.String(),
+		CreatedAt: u.CreatedAt,
+	}
+	if !u.PurgeAt.IsZero() {
+		p.PurgeAt = &u.PurgeAt
+	}
+	return p
+}
+
+// UserDataExportFromProto is
+func UserDataExportFromProto(pb *proto.UserDataExport) *UserDataExport {
+	export := &UserDataExport{
+		UserUUID: uuid.FromBytesOrNil(pb.UserUuid),
+		Document: pb.Document,
+	}
+	if pb.ExportedAt != nil {
+		export.ExportedAt = pb.ExportedAt.AsTime()
+	}
+	return export
+}
+
+// Data decodes the document
+func (e UserDataExport) Data() (*UserData, error) {
+	data := &UserData{}
+	if err := json.Unmarshal(e.Document, data); err != nil {
+		return nil, fmt.Errorf("decode user data export: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestUserDataProfileFormat(t *testing.T) {
+	u := User{
+		UserUUID:  uuid.Must(uuid.NewV4()),
+		Username:  "jdoe",
+		Email:     "jdoe@example.com",
+		FirstName: "J",
+		Status:    StatusActive,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	doc, err := json.Marshal(UserData{Profile: ProfileDataFromUser(u)})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, key := range []string{`"user_uuid"`, `"first_name"`, `"status":"` + StatusActive.String() + `"`, `"created_at"`} {
+		if !strings.Contains(string(doc), key) {
+			t.Fatalf("document %s has no %s", doc, key)
+		}
+	}
+	if strings.Contains(string(doc), "purge_at") || strings.Contains(string(doc), "UserUUID") {
+		t.Fatalf("unexpected field in %s", doc)
+	}
+
+	data, err := UserDataExport{Document: doc}.Data()
+	if err != nil {
+		t.Fatalf("Data: %v", err)
+	}
+	if data.Profile.UserUUID != u.UserUUID || data.Profile.Email != u.Email {
+		t.Fatalf("decoded profile %+v", data.Profile)
+	}
+}
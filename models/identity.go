@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	// ErrIdentityNotLinked is returned for external identities not linked to any user
+	ErrIdentityNotLinked = errors.New("external identity is not linked")
+	// ErrIdentityAlreadyLinked is returned when the external identity is linked to another user
+	// or the user already has an identity of the provider
+	ErrIdentityAlreadyLinked = errors.New("external identity is already linked")
+	// ErrNonceRequired is returned for external ID tokens sent without the nonce of the sign in
+	ErrNonceRequired = errors.New("nonce is required")
+)
+
+// ExternalIdentity links the subject of an external identity provider to a user
+type ExternalIdentity struct {
+	Provider string
+	Subject  string
+	UserUUID uuid.UUID
+	Email    string
+	LinkedAt time.Time
+}
+
+// ExternalIdentityFromProto is
+func ExternalIdentityFromProto(pb *proto.ExternalIdentity) *ExternalIdentity {
+	i := &ExternalIdentity{
+		Provider: pb.Provider,
+		Subject:  pb.Subject,
+		UserUUID: uuid.FromBytesOrNil(pb.UserUuid),
+		Email:    pb.Email,
+	}
+	if pb.LinkedAt != nil {
+		i.LinkedAt = pb.LinkedAt.AsTime()
+	}
+	return i
+}
+
+func (i ExternalIdentity) Proto() *proto.ExternalIdentity {
+	pb := &proto.ExternalIdentity{
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		UserUuid: i.UserUUID.Bytes(),
+		Email:    i.Email,
+	}
+	if !i.LinkedAt.IsZero() {
+		pb.LinkedAt = timestamppb.New(i.LinkedAt)
+	}
+	return pb
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+)
+
+// PasswordHashFormat is the algorithm of an imported password hash
+type PasswordHashFormat int
+
+const (
+	HashUnknown      = PasswordHashFormat(proto.PasswordHashFormat_PASSWORD_HASH_FORMAT_UNKNOWN)
+	HashBcrypt       = PasswordHashFormat(proto.PasswordHashFormat_PASSWORD_HASH_FORMAT_BCRYPT)
+	HashArgon2id     = PasswordHashFormat(proto.PasswordHashFormat_PASSWORD_HASH_FORMAT_ARGON2ID)
+	HashPBKDF2SHA256 = PasswordHashFormat(proto.PasswordHashFormat_PASSWORD_HASH_FORMAT_PBKDF2_SHA256)
+)
+
+// hashSyntax is the standard encoding of each format: modular crypt for bcrypt,
+// PHC string for argon2id and the Django encoding for pbkdf2_sha256
+var hashSyntax = map[PasswordHashFormat]*regexp.Regexp{
+	HashBcrypt:       regexp.MustCompile(`^\$2[aby]\$\d\d\$[./A-Za-z0-9]{53}$`),
+	HashArgon2id:     regexp.MustCompile(`^\$argon2id\$v=\d+\$m=\d+,t=\d+,p=\d+\$[A-Za-z0-9+/]+\$[A-Za-z0-9+/]+$`),
+	HashPBKDF2SHA256: regexp.MustCompile(`^pbkdf2_sha256\$\d+\$[^$]+\$[A-Za-z0-9+/]+=*$`),
+}
+
+func (f PasswordHashFormat) String() string {
+	switch f {
+	case HashBcrypt:
+		return "bcrypt"
+	case HashArgon2id:
+		return "argon2id"
+	case HashPBKDF2SHA256:
+		return "pbkdf2_sha256"
+	}
+	return "unknown"
+}
+
+// ParsePasswordHashFormat parses the name returned by String
+func ParsePasswordHashFormat(s string) (PasswordHashFormat, error) {
+	for _, f := range []PasswordHashFormat{HashBcrypt, HashArgon2id, HashPBKDF2SHA256} {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+	return HashUnknown, fmt.Errorf("unsupported password hash format %q", s)
+}
+
+// DetectPasswordHashFormat returns the format whose encoding the hash has
+func DetectPasswordHashFormat(hash string) PasswordHashFormat {
+	for f, re := range hashSyntax {
+		if re.MatchString(hash) {
+			return f
+		}
+	}
+	return HashUnknown
+}
+
+// ImportRecord is one user of ImportUsers, PasswordHash is empty for users without password
+type ImportRecord struct {
+	User         User
+	PasswordHash string
+	HashFormat   PasswordHashFormat
+}
+
+// Validate checks the user and that the hash is encoded as its format
+func (r ImportRecord) Validate() error {
+	errs := &ValidationError{}
+	if err := r.User.Validate(); err != nil {
+		ve, _ := ValidationErrorFromError(err)
+		errs.Merge(ve)
+	}
+	if r.PasswordHash != "" {
+		re, ok := hashSyntax[r.HashFormat]
+		switch {
+		case !ok:
+			errs.Add("hash_format", "must be bcrypt, argon2id or pbkdf2_sha256")
+		case !re.MatchString(r.PasswordHash):
+			errs.Add("password_hash", "is not a valid "+r.HashFormat.String()+" hash")
+		}
+	}
+	return errs.Err()
+}
+
+// Proto is
+func (r ImportRecord) Proto(index int) *proto.ImportUserRecord {
+	return &proto.ImportUserRecord{
+		Index:        int64(index),
+		User:         r.User.Proto(),
+		PasswordHash: r.PasswordHash,
+		HashFormat:   proto.PasswordHashFormat(r.HashFormat),
+	}
+}
+
+// ImportError is a record that was not imported. Index is the position
+// of the record in the import.
+type ImportError struct {
+	Index   int
+	Email   string
+	Message string
+}
+
+func (e *ImportError) Error() string {
+	if e.Email == "" {
+		return fmt.Sprintf("record %d: %s", e.Index, e.Message)
+	}
+	return fmt.Sprintf("record %d (%s): %s", e.Index, e.Email, e.Message)
+}
+
+// MaxImportErrors is the number of failed records listed in an ImportResult
+const MaxImportErrors = 1000
+
+// ImportResult is the outcome of ImportUsers, Skipped counts users whose email existed.
+// Errors lists the first MaxImportErrors failed records by index,
+// ErrorsTruncated counts the failed records left out.
+type ImportResult struct {
+	Created         int
+	Skipped         int
+	Failed          int
+	Errors          []*ImportError
+	ErrorsTruncated int
+	DryRun          bool
+}
+
+// AddError counts a failed record and lists it while there is room
+func (r *ImportResult) AddError(e *ImportError) {
+	r.Failed++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, e)
+		return
+	}
+	r.ErrorsTruncated++
+}
+
+// Merge adds the counts and errors of other, keeping the first MaxImportErrors errors by index
+func (r *ImportResult) Merge(other *ImportResult) {
+	r.Created += other.Created
+	r.Skipped += other.Skipped
+	r.Failed += other.Failed
+	r.ErrorsTruncated += other.ErrorsTruncated
+
+	r.Errors = append(r.Errors, other.Errors...)
+	sort.Slice(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Index < r.Errors[j].Index
+	})
+	if n := len(r.Errors) - MaxImportErrors; n > 0 {
+		r.ErrorsTruncated += n
+		r.Errors = r.Errors[:MaxImportErrors:MaxImportErrors]
+	}
+}
+
+// ImportResultFromProto is
+func ImportResultFromProto(pb *proto.ImportUsersResponse) *ImportResult {
+	r := &ImportResult{
+		Created: int(pb.Created),
+		Skipped: int(pb.Skipped),
+		Failed:  int(pb.Failed),
+		DryRun:  pb.DryRun,
+
+		ErrorsTruncated: int(pb.ErrorsTruncated),
+	}
+	for _, e := range pb.Errors {
+		r.Errors = append(r.Errors, &ImportError{
+			Index:   int(e.Index),
+			Email:   e.Email,
+			Message: e.Message,
+		})
+	}
+	return r
+}
+
+// Proto is
+func (r ImportResult) Proto() *proto.ImportUsersResponse {
+	pb := &proto.ImportUsersResponse{
+		Created:         int64(r.Created),
+		Skipped:         int64(r.Skipped),
+		Failed:          int64(r.Failed),
+		DryRun:          r.DryRun,
+		ErrorsTruncated: int64(r.ErrorsTruncated),
+	}
+	for _, e := range r.Errors {
+		pb.Errors = append(pb.Errors, &proto.ImportRecordError{
+			Index:   int64(e.Index),
+			Email:   e.Email,
+			Message: e.Message,
+		})
+	}
+	return pb
+}
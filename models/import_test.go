@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestImportResultCapsErrors(t *testing.T) {
+	server := &ImportResult{}
+	client := &ImportResult{}
+	for i := range MaxImportErrors + 10 {
+		// the server lists odd records, the client rejected the even ones
+		if i%2 == 1 {
+			server.AddError(&ImportError{Index: i})
+		} else {
+			client.AddError(&ImportError{Index: i})
+		}
+	}
+	if len(server.Errors) != (MaxImportErrors+10)/2 || server.ErrorsTruncated != 0 {
+		t.Fatalf("server listed %d, truncated %d", len(server.Errors), server.ErrorsTruncated)
+	}
+
+	server.Merge(client)
+	if server.Failed != MaxImportErrors+10 {
+		t.Fatalf("Failed = %d, want %d", server.Failed, MaxImportErrors+10)
+	}
+	if len(server.Errors) != MaxImportErrors || server.ErrorsTruncated != 10 {
+		t.Fatalf("listed %d, truncated %d", len(server.Errors), server.ErrorsTruncated)
+	}
+	for i, e := range server.Errors {
+		if e.Index != i {
+			t.Fatalf("Errors[%d].Index = %d, want the first errors by index", i, e.Index)
+		}
+	}
+
+	pb := server.Proto()
+	back := ImportResultFromProto(pb)
+	if back.ErrorsTruncated != 10 || len(back.Errors) != MaxImportErrors {
+		t.Fatalf("proto round trip lost the truncation: %d, %d", back.ErrorsTruncated, len(back.Errors))
+	}
+}
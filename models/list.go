@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListUsersFilter selects users for ListUsers, zero fields match everything
+type ListUsersFilter struct {
+	UserTypes     []int
+	Statuses      []UserStatus
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Query is a prefix of email, username, first or last name
+	Query string
+	// PageSize is the server default when zero
+	PageSize int
+}
+
+// UsersPage is one page of ListUsers, NextCursor is empty on the last page
+type UsersPage struct {
+	Users      []*User
+	NextCursor string
+}
+
+// Proto is
+func (f ListUsersFilter) Proto(cursor string) *proto.ListUsersRequest {
+	req := &proto.ListUsersRequest{
+		PageSize: int32(f.PageSize),
+		Cursor:   cursor,
+		Query:    f.Query,
+	}
+	for _, t := range f.UserTypes {
+		req.UserTypes = append(req.UserTypes, int64(t))
+	}
+	for _, s := range f.Statuses {
+		req.Statuses = append(req.Statuses, proto.UserStatus(s))
+	}
+	if !f.CreatedAfter.IsZero() {
+		req.CreatedAfter = timestamppb.New(f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		req.CreatedBefore = timestamppb.New(f.CreatedBefore)
+	}
+	return req
+}
+
+// ListUsersFilterFromProto is
+func ListUsersFilterFromProto(pb *proto.ListUsersRequest) *ListUsersFilter {
+	f := &ListUsersFilter{
+		PageSize: int(pb.PageSize),
+		Query:    pb.Query,
+	}
+	for _, t := range pb.UserTypes {
+		f.UserTypes = append(f.UserTypes, int(t))
+	}
+	for _, s := range pb.Statuses {
+		f.Statuses = append(f.Statuses, UserStatus(s))
+	}
+	if pb.CreatedAfter != nil {
+		f.CreatedAfter = pb.CreatedAfter.AsTime()
+	}
+	if pb.CreatedBefore != nil {
+		f.CreatedBefore = pb.CreatedBefore.AsTime()
+	}
+	return f
+}
+
+// UsersPageFromProto is
+func UsersPageFromProto(pb *proto.ListUsersResponse) *UsersPage {
+	page := &UsersPage{
+		Users:      make([]*User, 0, len(pb.Users)),
+		NextCursor: pb.NextCursor,
+	}
+	for _, u := range pb.Users {
+		page.Users = append(page.Users, UserFromProto(u))
+	}
+	return page
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+)
+
+// ServiceClient is a machine client authenticating with client credentials.
+// ClientSecret is only returned by RegisterServiceClient.
+type ServiceClient struct {
+	ClientID     string
+	ClientSecret []byte
+	Name         string
+	Scopes       []string
+}
+
+// ServiceToken is a short-lived token of a ServiceClient
+type ServiceToken struct {
+	Token     []byte
+	ExpiresAt time.Time
+	Scopes    []string
+}
+
+// ServiceClientFromProto is
+func ServiceClientFromProto(pb *proto.ServiceClient) *ServiceClient {
+	return &ServiceClient{
+		ClientID:     pb.ClientId,
+		ClientSecret: pb.ClientSecret,
+		Name:         pb.Name,
+		Scopes:       pb.Scopes,
+	}
+}
+
+// ServiceTokenFromProto is
+func ServiceTokenFromProto(pb *proto.ServiceToken) *ServiceToken {
+	t := &ServiceToken{
+		Token:  pb.Token,
+		Scopes: pb.Scopes,
+	}
+	if pb.ExpiresAt != nil {
+		t.ExpiresAt = pb.ExpiresAt.AsTime()
+	}
+	return t
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserStatus is the lifecycle state of a user account
+type UserStatus int
+
+const (
+	StatusActive      = UserStatus(proto.UserStatus_USER_STATUS_ACTIVE)
+	StatusDeactivated = UserStatus(proto.UserStatus_USER_STATUS_DEACTIVATED)
+	StatusDeleted     = UserStatus(proto.UserStatus_USER_STATUS_DELETED)
+)
+
+func (s UserStatus) String() string {
+	switch s {
+	case StatusActive:
+		return "active"
+	case StatusDeactivated:
+		return "deactivated"
+	case StatusDeleted:
+		return "deleted"
+	}
+	return fmt.Sprintf("UserStatus(%d)", int(s))
+}
+
+// ParseUserStatus parses the name returned by String
+func ParseUserStatus(s string) (UserStatus, error) {
+	for _, st := range []UserStatus{StatusActive, StatusDeactivated, StatusDeleted} {
+		if strings.EqualFold(s, st.String()) {
+			return st, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid status %q", s)
+}
+
+// ErrorDomain is the ErrorInfo domain of errors sent by the user service
+const ErrorDomain = "userapi"
+
+var (
+	// ErrUserDeactivated is matched by errors.Is for an AccountStatusError of a deactivated user
+	ErrUserDeactivated = errors.New("user is deactivated")
+	// ErrUserDeleted is matched by errors.Is for an AccountStatusError of a deleted user
+	ErrUserDeleted = errors.New("user is deleted")
+)
+
+var statusReasons = map[UserStatus]string{
+	StatusDeactivated: "USER_DEACTIVATED",
+	StatusDeleted:     "USER_DELETED",
+}
+
+// AccountStatusError is returned by CheckAuth and SignIn for accounts that are not active
+type AccountStatusError struct {
+	Status UserStatus
+}
+
+func (e *AccountStatusError) Error() string {
+	return "account is " + e.Status.String()
+}
+
+// Is makes errors.Is(err, ErrUserDeactivated) and errors.Is(err, ErrUserDeleted) work
+func (e *AccountStatusError) Is(target error) bool {
+	switch target {
+	case ErrUserDeactivated:
+		return e.Status == StatusDeactivated
+	case ErrUserDeleted:
+		return e.Status == StatusDeleted
+	}
+	return false
+}
+
+// GRPCStatus is used by grpc status.FromError and by servers returning the error as is
+func (e *AccountStatusError) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, e.Error())
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: statusReasons[e.Status],
+		Domain: ErrorDomain,
+	})
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// AccountStatusErrorFromError extracts an AccountStatusError from a local
+// error or from a gRPC FailedPrecondition status with ErrorInfo details
+func AccountStatusErrorFromError(err error) (*AccountStatusError, bool) {
+	var se *AccountStatusError
+	if errors.As(err, &se) {
+		return se, true
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return nil, false
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != ErrorDomain {
+			continue
+		}
+		for s, reason := range statusReasons {
+			if info.Reason == reason {
+				return &AccountStatusError{Status: s}, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUserStatusString(t *testing.T) {
+	for _, st := range []UserStatus{StatusActive, StatusDeactivated, StatusDeleted} {
+		parsed, err := ParseUserStatus(st.String())
+		if err != nil || parsed != st {
+			t.Fatalf("ParseUserStatus(%q) = %v, %v", st.String(), parsed, err)
+		}
+	}
+	if st, err := ParseUserStatus("Deactivated"); err != nil || st != StatusDeactivated {
+		t.Fatalf("ParseUserStatus is case sensitive: %v, %v", st, err)
+	}
+	for _, s := range []string{"", "inactive", "UserStatus(0)"} {
+		if _, err := ParseUserStatus(s); err == nil {
+			t.Fatalf("ParseUserStatus(%q) accepted", s)
+		}
+	}
+	if s := UserStatus(42).String(); s != "UserStatus(42)" {
+		t.Fatalf("String of an unknown status = %q", s)
+	}
+}
+
+func TestAccountStatusErrorGRPCRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		status UserStatus
+		is     error
+		reason string
+	}{
+		{StatusDeactivated, ErrUserDeactivated, "USER_DEACTIVATED"},
+		{StatusDeleted, ErrUserDeleted, "USER_DELETED"},
+	} {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			st := status.Convert(fmt.Errorf("signIn: %w", &AccountStatusError{Status: tt.status}))
+			if st.Code() != codes.FailedPrecondition {
+				t.Fatalf("code = %s, want FailedPrecondition", st.Code())
+			}
+			info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+			if !ok || info.Reason != tt.reason || info.Domain != ErrorDomain {
+				t.Fatalf("details = %v, want ErrorInfo %s in %s", st.Details(), tt.reason, ErrorDomain)
+			}
+
+			b, err := proto.Marshal(st.Proto())
+			if err != nil {
+				t.Fatalf("marshal status: %v", err)
+			}
+			var pb spb.Status
+			if err := proto.Unmarshal(b, &pb); err != nil {
+				t.Fatalf("unmarshal status: %v", err)
+			}
+			got, ok := AccountStatusErrorFromError(status.FromProto(&pb).Err())
+			if !ok || got.Status != tt.status {
+				t.Fatalf("AccountStatusErrorFromError = %v, %t, want status %s", got, ok, tt.status)
+			}
+			if !errors.Is(got, tt.is) {
+				t.Fatalf("errors.Is(%v, %v) = false", got, tt.is)
+			}
+		})
+	}
+
+	deactivated := &AccountStatusError{Status: StatusDeactivated}
+	if errors.Is(deactivated, ErrUserDeleted) {
+		t.Fatal("a deactivated account matches ErrUserDeleted")
+	}
+	foreign, _ := status.New(codes.FailedPrecondition, "busy").WithDetails(&errdetails.ErrorInfo{
+		Reason: "USER_DELETED", Domain: "other",
+	})
+	if got, ok := AccountStatusErrorFromError(foreign.Err()); ok {
+		t.Fatalf("ErrorInfo of another domain was taken: %v", got)
+	}
+	if got, ok := AccountStatusErrorFromError(status.Error(codes.FailedPrecondition, "no details")); ok {
+		t.Fatalf("status without details was taken: %v", got)
+	}
+}
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"time"
+
 	proto "github.com/garden-raccoon/user-pkg/protocols/user"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type User struct {
@@ -13,6 +16,11 @@ type User struct {
 	UserType  int
 	FirstName string
 	LastName  string
+	Avatar    string
+	Status    UserStatus
+	// PurgeAt is when a deleted user is hard purged
+	PurgeAt   time.Time
+	CreatedAt time.Time
 }
 
 type UpdateUserRequest struct {
@@ -26,20 +34,41 @@ type UpdateUserRequest struct {
 
 // UserFromProto is
 func UserFromProto(pb *proto.User) *User {
-	return &User{
-		UserUUID: uuid.FromBytesOrNil(pb.UserUuid),
-		Email:    pb.Email,
-		Username: pb.Username,
-		UserType: int(pb.UserType),
+	u := &User{
+		UserUUID:  uuid.FromBytesOrNil(pb.UserUuid),
+		Email:     pb.Email,
+		Username:  pb.Username,
+		UserType:  int(pb.UserType),
+		FirstName: pb.FirstName,
+		LastName:  pb.LastName,
+		Avatar:    pb.Avatar,
+		Status:    UserStatus(pb.Status),
+	}
+	if pb.PurgeAt != nil {
+		u.PurgeAt = pb.PurgeAt.AsTime()
 	}
+	if pb.CreatedAt != nil {
+		u.CreatedAt = pb.CreatedAt.AsTime()
+	}
+	return u
 }
 
 func (u User) Proto() *proto.User {
 	employer := &proto.User{
-		UserUuid: u.UserUUID.Bytes(),
-		Username: u.Username,
-		Email:    u.Email,
-		UserType: int64(u.UserType),
+		UserUuid:  u.UserUUID.Bytes(),
+		Username:  u.Username,
+		Email:     u.Email,
+		UserType:  int64(u.UserType),
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Avatar:    u.Avatar,
+		Status:    proto.UserStatus(u.Status),
+	}
+	if !u.PurgeAt.IsZero() {
+		employer.PurgeAt = timestamppb.New(u.PurgeAt)
+	}
+	if !u.CreatedAt.IsZero() {
+		employer.CreatedAt = timestamppb.New(u.CreatedAt)
 	}
 	return employer
 }
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	maxEmailLength    = 254
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	maxNameLength     = 64
+	maxAvatarLength   = 2048
+)
+
+// Validate checks every field of the user and returns a *ValidationError listing all violations
+func (u User) Validate() error {
+	errs := &ValidationError{}
+	checkUUID(errs, u.UserUUID)
+	checkEmail(errs, u.Email)
+	if u.Username != "" {
+		checkUsername(errs, u.Username)
+	}
+	checkName(errs, "first_name", u.FirstName)
+	checkName(errs, "last_name", u.LastName)
+	if u.Avatar != "" {
+		checkAvatar(errs, u.Avatar)
+	}
+	return errs.Err()
+}
+
+// Validate checks the fields that are set and returns a *ValidationError listing all violations
+func (u UpdateUserRequest) Validate() error {
+	errs := &ValidationError{}
+	checkUUID(errs, u.UserUUID)
+	if u.Email != nil {
+		checkEmail(errs, *u.Email)
+	}
+	if u.Username != nil {
+		checkUsername(errs, *u.Username)
+	}
+	if u.FirstName != nil {
+		checkName(errs, "first_name", *u.FirstName)
+	}
+	if u.LastName != nil {
+		checkName(errs, "last_name", *u.LastName)
+	}
+	if u.Avatar != nil {
+		checkAvatar(errs, *u.Avatar)
+	}
+	return errs.Err()
+}
+
+// EmailViolations returns the violations of a bare email, e.g. for SignUp
+func EmailViolations(email string) *ValidationError {
+	errs := &ValidationError{}
+	checkEmail(errs, email)
+	return errs
+}
+
+func checkUUID(errs *ValidationError, id uuid.UUID) {
+	if id == uuid.Nil {
+		errs.Add("user_uuid", "must not be nil")
+	}
+}
+
+func checkEmail(errs *ValidationError, email string) {
+	if email == "" {
+		errs.Add("email", "must not be empty")
+		return
+	}
+	if len(email) > maxEmailLength {
+		errs.Add("email", fmt.Sprintf("must be at most %d bytes long", maxEmailLength))
+		return
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		errs.Add("email", "must be a valid email address")
+	}
+}
+
+func checkUsername(errs *ValidationError, username string) {
+	if n := utf8.RuneCountInString(username); n < minUsernameLength || n > maxUsernameLength {
+		errs.Add("username", fmt.Sprintf("must be %d to %d characters long", minUsernameLength, maxUsernameLength))
+	}
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			errs.Add("username", "may contain only latin letters, digits, '.', '_' and '-'")
+			return
+		}
+	}
+}
+
+func isUsernameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '.' || r == '_' || r == '-'
+}
+
+func checkName(errs *ValidationError, field, name string) {
+	if !utf8.ValidString(name) {
+		errs.Add(field, "must be valid UTF-8")
+		return
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		errs.Add(field, fmt.Sprintf("must be at most %d characters long", maxNameLength))
+	}
+}
+
+func checkAvatar(errs *ValidationError, avatar string) {
+	if len(avatar) > maxAvatarLength {
+		errs.Add("avatar", fmt.Sprintf("must be at most %d bytes long", maxAvatarLength))
+		return
+	}
+	u, err := url.Parse(avatar)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		errs.Add("avatar", "must be an absolute http or https URL")
+	}
+}
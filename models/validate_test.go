@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+// violations returns the "field: description" of every violation of err
+func violations(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("error %v is not a *ValidationError", err)
+	}
+	var out []string
+	for _, v := range ve.Violations {
+		out = append(out, v.Field+": "+v.Description)
+	}
+	return out
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestUserValidate(t *testing.T) {
+	valid := func() User {
+		return User{UserUUID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Username: "jane_doe"}
+	}
+	tests := []struct {
+		name   string
+		change func(u *User)
+		want   []string
+	}{
+		{"valid", func(*User) {}, nil},
+		{"optional fields empty", func(u *User) { u.Username, u.Avatar = "", "" }, nil},
+		{"nil uuid", func(u *User) { u.UserUUID = uuid.Nil }, []string{"user_uuid: must not be nil"}},
+		{"no email", func(u *User) { u.Email = "" }, []string{"email: must not be empty"}},
+		{"invalid email", func(u *User) { u.Email = "jane" }, []string{"email: must be a valid email address"}},
+		{"email with name", func(u *User) { u.Email = "Jane <jane@example.com>" }, []string{"email: must be a valid email address"}},
+		{"long email", func(u *User) { u.Email = strings.Repeat("a", 250) + "@example.com" }, []string{"email: must be at most 254 bytes long"}},
+		{"short username", func(u *User) { u.Username = "jd" }, []string{"username: must be 3 to 32 characters long"}},
+		{"long username", func(u *User) { u.Username = strings.Repeat("j", 33) }, []string{"username: must be 3 to 32 characters long"}},
+		{"username characters", func(u *User) { u.Username = "jane doe" }, []string{"username: may contain only latin letters, digits, '.', '_' and '-'"}},
+		{"non-latin username", func(u *User) { u.Username = "жанна" }, []string{"username: may contain only latin letters, digits, '.', '_' and '-'"}},
+		{"long first name", func(u *User) { u.FirstName = strings.Repeat("é", 65) }, []string{"first_name: must be at most 64 characters long"}},
+		{"first name of 64 characters", func(u *User) { u.FirstName = strings.Repeat("é", 64) }, nil},
+		{"invalid last name", func(u *User) { u.LastName = "\xff" }, []string{"last_name: must be valid UTF-8"}},
+		{"relative avatar", func(u *User) { u.Avatar = "/avatar.png" }, []string{"avatar: must be an absolute http or https URL"}},
+		{"avatar scheme", func(u *User) { u.Avatar = "ftp://example.com/a.png" }, []string{"avatar: must be an absolute http or https URL"}},
+		{"long avatar", func(u *User) { u.Avatar = "https://example.com/" + strings.Repeat("a", 2048) }, []string{"avatar: must be at most 2048 bytes long"}},
+		{
+			"every violation", func(u *User) { *u = User{Username: "j", Avatar: "avatar"} },
+			[]string{
+				"user_uuid: must not be nil",
+				"email: must not be empty",
+				"username: must be 3 to 32 characters long",
+				"avatar: must be an absolute http or https URL",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := valid()
+			tt.change(&u)
+			if got := violations(t, u.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequestValidate(t *testing.T) {
+	id := uuid.Must(uuid.NewV4())
+	tests := []struct {
+		name string
+		req  UpdateUserRequest
+		want []string
+	}{
+		{"nothing set", UpdateUserRequest{UserUUID: id}, nil},
+		{"nil uuid", UpdateUserRequest{}, []string{"user_uuid: must not be nil"}},
+		{"valid fields", UpdateUserRequest{
+			UserUUID: id, Email: ptr("jane@example.com"), Username: ptr("jane"),
+			FirstName: ptr("Jane"), LastName: ptr("Doe"), Avatar: ptr("https://example.com/a.png"),
+		}, nil},
+		// a set field is checked even when empty, unlike the optional fields of User
+		{"empty email", UpdateUserRequest{UserUUID: id, Email: ptr("")}, []string{"email: must not be empty"}},
+		{"empty username", UpdateUserRequest{UserUUID: id, Username: ptr("")}, []string{"username: must be 3 to 32 characters long"}},
+		{"empty avatar", UpdateUserRequest{UserUUID: id, Avatar: ptr("")}, []string{"avatar: must be an absolute http or https URL"}},
+		{"empty names", UpdateUserRequest{UserUUID: id, FirstName: ptr(""), LastName: ptr("")}, nil},
+		{"invalid fields", UpdateUserRequest{
+			UserUUID: id, Email: ptr("jane@"), Username: ptr("jane!"),
+			FirstName: ptr(strings.Repeat("j", 65)), LastName: ptr("\xff"), Avatar: ptr("example.com"),
+		}, []string{
+			"email: must be a valid email address",
+			"username: may contain only latin letters, digits, '.', '_' and '-'",
+			"first_name: must be at most 64 characters long",
+			"last_name: must be valid UTF-8",
+			"avatar: must be an absolute http or https URL",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violations(t, tt.req.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailViolations(t *testing.T) {
+	tests := map[string][]string{
+		"jane@example.com":     nil,
+		"jane+tag@example.com": nil,
+		"":                     {"email: must not be empty"},
+		"jane@":                {"email: must be a valid email address"},
+		" jane@example.com":    {"email: must be a valid email address"},
+	}
+	for email, want := range tests {
+		if got := violations(t, EmailViolations(email).Err()); !reflect.DeepEqual(got, want) {
+			t.Errorf("EmailViolations(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
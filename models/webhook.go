@@ -0,0 +1,92 @@
+package models
+
+import (
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+)
+
+// Webhook is an HTTP endpoint receiving user events.
+// Secret signs the payloads and is only returned by RegisterWebhook.
+type Webhook struct {
+	WebhookUUID uuid.UUID
+	URL         string
+	EventTypes  []UserEventType
+	Secret      []byte
+}
+
+// Wants reports whether the webhook subscribed to events of type t
+func (w Webhook) Wants(t UserEventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, et := range w.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook
+type WebhookDelivery struct {
+	DeliveryUUID uuid.UUID
+	WebhookUUID  uuid.UUID
+	Event        *UserEvent
+	Attempts     int
+	LastError    string
+}
+
+// WebhookFromProto is
+func WebhookFromProto(pb *proto.Webhook) *Webhook {
+	w := &Webhook{
+		WebhookUUID: uuid.FromBytesOrNil(pb.WebhookUuid),
+		URL:         pb.Url,
+		Secret:      pb.Secret,
+	}
+	for _, t := range pb.EventTypes {
+		w.EventTypes = append(w.EventTypes, UserEventType(t))
+	}
+	return w
+}
+
+// Proto is
+func (w Webhook) Proto() *proto.Webhook {
+	pb := &proto.Webhook{
+		WebhookUuid: w.WebhookUUID.Bytes(),
+		Url:         w.URL,
+		Secret:      w.Secret,
+	}
+	for _, t := range w.EventTypes {
+		pb.EventTypes = append(pb.EventTypes, proto.UserEventType(t))
+	}
+	return pb
+}
+
+// WebhookDeliveryFromProto is
+func WebhookDeliveryFromProto(pb *proto.WebhookDelivery) *WebhookDelivery {
+	d := &WebhookDelivery{
+		DeliveryUUID: uuid.FromBytesOrNil(pb.DeliveryUuid),
+		WebhookUUID:  uuid.FromBytesOrNil(pb.WebhookUuid),
+		Attempts:     int(pb.Attempts),
+		LastError:    pb.LastError,
+	}
+	if pb.Event != nil {
+		d.Event = UserEventFromProto(pb.Event)
+	}
+	return d
+}
+
+// Proto is
+func (d WebhookDelivery) Proto() *proto.WebhookDelivery {
+	pb := &proto.WebhookDelivery{
+		DeliveryUuid: d.DeliveryUUID.Bytes(),
+		WebhookUuid:  d.WebhookUUID.Bytes(),
+		Attempts:     int32(d.Attempts),
+		LastError:    d.LastError,
+	}
+	if d.Event != nil {
+		pb.Event = d.Event.Proto()
+	}
+	return pb
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+var (
+	ErrClientNotFound     = errors.New("client not found")
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+)
+
+// Client is a first-party relying party. First-party clients are trusted,
+// so users are never asked for consent.
+type Client struct {
+	ID           string
+	Name         string
+	RedirectURIs []string
+	// Secret is empty for public clients, which authenticate with PKCE only
+	Secret string
+}
+
+// Public reports whether the client has no secret
+func (c *Client) Public() bool {
+	return c.Secret == ""
+}
+
+// allowsRedirect reports whether uri is registered, compared exactly,
+// and secure, as clients of other stores are not checked on registration
+func (c *Client) allowsRedirect(uri string) bool {
+	if !slices.Contains(c.RedirectURIs, uri) {
+		return false
+	}
+	u, err := url.Parse(uri)
+	return err == nil && secureRedirect(u)
+}
+
+func (c *Client) checkSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(c.Secret), []byte(secret)) == 1
+}
+
+// ClientStore looks up registered clients
+type ClientStore interface {
+	Client(id string) (*Client, error)
+}
+
+// MemoryClients is a ClientStore kept in memory
+type MemoryClients struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// NewMemoryClients is
+func NewMemoryClients() *MemoryClients {
+	return &MemoryClients{clients: make(map[string]*Client)}
+}
+
+// Register adds a client with generated credentials. Confidential clients
+// get a secret, keep it, it is not returned again.
+func (m *MemoryClients) Register(name string, redirectURIs []string, public bool) (*Client, error) {
+	if len(redirectURIs) == 0 {
+		return nil, fmt.Errorf("%w: at least one is required", ErrInvalidRedirectURI)
+	}
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" || !secureRedirect(u) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRedirectURI, uri)
+		}
+	}
+
+	id, err := randomString(16)
+	if err != nil {
+		return nil, fmt.Errorf("client id: %w", err)
+	}
+	c := &Client{
+		ID:           id,
+		Name:         name,
+		RedirectURIs: slices.Clone(redirectURIs),
+	}
+	if !public {
+		if c.Secret, err = randomString(32); err != nil {
+			return nil, fmt.Errorf("client secret: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[c.ID] = c
+	cc := *c
+	return &cc, nil
+}
+
+// Unregister removes the client
+func (m *MemoryClients) Unregister(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.clients[id]; !ok {
+		return ErrClientNotFound
+	}
+	delete(m.clients, id)
+	return nil
+}
+
+// Client is
+func (m *MemoryClients) Client(id string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	cc := *c
+	return &cc, nil
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b64(b), nil
+}
+
+// secureRedirect allows https, and http only to the loopback interface
+// for native apps (RFC 8252)
+func secureRedirect(u *url.URL) bool {
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/internal/bearer"
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	idTokenType     = "JWT"
+	accessTokenType = "at+jwt"
+)
+
+var (
+	errNoSession          = errors.New("no valid session")
+	errSessionUnavailable = errors.New("session verification unavailable")
+)
+
+// Error is an OAuth 2.0 error response
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Claims are the standard claims about the user released for the granted scopes
+type Claims struct {
+	Subject           string `json:"sub"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Email             string `json:"email,omitempty"`
+}
+
+// ClaimsFor returns the claims of user released for scopes
+func ClaimsFor(user *models.User, scopes []string) Claims {
+	c := Claims{Subject: user.UserUUID.String()}
+	if slices.Contains(scopes, scopeProfile) {
+		c.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		c.GivenName = user.FirstName
+		c.FamilyName = user.LastName
+		c.PreferredUsername = user.Username
+		c.Picture = user.Avatar
+	}
+	if slices.Contains(scopes, scopeEmail) {
+		c.Email = user.Email
+	}
+	return c
+}
+
+type idTokenClaims struct {
+	Claims
+	Issuer   string `json:"iss"`
+	Audience string `json:"aud"`
+	IssuedAt int64  `json:"iat"`
+	Expiry   int64  `json:"exp"`
+	AuthTime int64  `json:"auth_time"`
+	Nonce    string `json:"nonce,omitempty"`
+}
+
+type accessTokenClaims struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	Audience string `json:"aud"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	IssuedAt int64  `json:"iat"`
+	Expiry   int64  `json:"exp"`
+}
+
+// TokenResponse is the token endpoint response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// authCode is an issued authorization code, it is redeemed once
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	userUUID      uuid.UUID
+	scopes        []string
+	nonce         string
+	codeChallenge string
+	authTime      time.Time
+	expires       time.Time
+}
+
+// authorize handles the authorization request of the code flow. Users
+// with a session get a code right away, first-party clients need no consent.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	q := r.Form
+
+	// nothing is redirected before the client and redirect uri are known good
+	client, err := p.clients.Client(q.Get("client_id"))
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid_request", "unknown client_id")
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "client lookup failed")
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	if !client.allowsRedirect(redirectURI) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "redirect_uri is not registered")
+		return
+	}
+	fail := func(code, description string) {
+		redirectWith(w, r, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {q.Get("state")},
+		})
+	}
+
+	if q.Get("response_type") != responseTypeCode {
+		fail("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	scopes := strings.Fields(q.Get("scope"))
+	if !slices.Contains(scopes, scopeOpenID) {
+		fail("invalid_scope", "the openid scope is required")
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != codeChallengeS256 {
+		fail("invalid_request", "a S256 code_challenge is required")
+		return
+	}
+
+	user, err := p.sessionUser(r)
+	switch {
+	case errors.Is(err, errNoSession):
+		if p.loginURL == "" || q.Get("prompt") == "none" {
+			fail("login_required", "the user is not signed in")
+			return
+		}
+		redirectWith(w, r, p.loginURL, url.Values{
+			"return_to": {p.issuer + authorizePath + "?" + q.Encode()},
+		})
+		return
+	case err != nil:
+		fail("temporarily_unavailable", err.Error())
+		return
+	}
+
+	code, err := randomString(32)
+	if err != nil {
+		fail("server_error", "code generation failed")
+		return
+	}
+	now := p.now()
+	p.storeCode(code, authCode{
+		clientID:      client.ID,
+		redirectURI:   redirectURI,
+		userUUID:      user.UserUUID,
+		scopes:        scopes,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		authTime:      now,
+		expires:       now.Add(p.codeTTL),
+	})
+	redirectWith(w, r, redirectURI, url.Values{"code": {code}, "state": {q.Get("state")}})
+}
+
+// sessionUser returns the user of the session token sent as bearer token or cookie
+func (p *Provider) sessionUser(r *http.Request) (*models.User, error) {
+	token, ok := bearer.Token(r.Header.Get("Authorization"))
+	if !ok {
+		c, err := r.Cookie(p.sessionCookie)
+		if err != nil || c.Value == "" {
+			return nil, errNoSession
+		}
+		token = c.Value
+	}
+
+	user, err := p.users.CheckAuth([]byte(token))
+	if err != nil {
+		if _, ok := models.AccountStatusErrorFromError(err); ok {
+			return nil, errNoSession
+		}
+		switch status.Code(err) {
+		case codes.Unauthenticated, codes.InvalidArgument, codes.NotFound, codes.PermissionDenied:
+			return nil, errNoSession
+		}
+		return nil, errSessionUnavailable
+	}
+	if user == nil {
+		return nil, errNoSession
+	}
+	return user, nil
+}
+
+func (p *Provider) storeCode(code string, ac authCode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for c, e := range p.codes {
+		if !now.Before(e.expires) {
+			delete(p.codes, c)
+		}
+	}
+	p.codes[code] = ac
+}
+
+// redeemCode removes the code, so a replayed code is rejected
+func (p *Provider) redeemCode(code string) (authCode, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ac, ok := p.codes[code]
+	delete(p.codes, code)
+	return ac, ok && p.now().Before(ac.expires)
+}
+
+// token exchanges an authorization code for an ID and an access token
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != grantAuthorizationCode {
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	client, ok := p.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	ac, ok := p.redeemCode(r.PostForm.Get("code"))
+	if !ok || ac.clientID != client.ID || ac.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired code")
+		return
+	}
+	if !verifyPKCE(r.PostForm.Get("code_verifier"), ac.codeChallenge) {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match")
+		return
+	}
+
+	user, err := p.users.UserByUUID(ac.userUUID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid_grant", "the user no longer exists")
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "user lookup failed")
+		return
+	}
+	if user.Status != models.StatusActive {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "the user is not active")
+		return
+	}
+
+	now := p.now()
+	exp := now.Add(p.tokenTTL)
+	idToken, err := p.signer.sign(idTokenType, idTokenClaims{
+		Claims:   ClaimsFor(user, ac.scopes),
+		Issuer:   p.issuer,
+		Audience: client.ID,
+		IssuedAt: now.Unix(),
+		Expiry:   exp.Unix(),
+		AuthTime: ac.authTime.Unix(),
+		Nonce:    ac.nonce,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "id token signing failed")
+		return
+	}
+	scope := strings.Join(ac.scopes, " ")
+	accessToken, err := p.signer.sign(accessTokenType, accessTokenClaims{
+		Issuer:   p.issuer,
+		Subject:  user.UserUUID.String(),
+		Audience: p.issuer + userinfoPath,
+		ClientID: client.ID,
+		Scope:    scope,
+		IssuedAt: now.Unix(),
+		Expiry:   exp.Unix(),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "access token signing failed")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.tokenTTL / time.Second),
+		IDToken:     idToken,
+		Scope:       scope,
+	})
+}
+
+// authenticateClient checks client_secret_basic or client_secret_post
+// credentials, public clients only send their client_id
+func (p *Provider) authenticateClient(w http.ResponseWriter, r *http.Request) (*Client, bool) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 2.3.1 form encodes the credentials before basic encoding
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	unauthorized := func() (*Client, bool) {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oidc"`)
+		}
+		writeError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return nil, false
+	}
+
+	client, err := p.clients.Client(id)
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			return unauthorized()
+		}
+		writeError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "client lookup failed")
+		return nil, false
+	}
+	if !client.Public() && !client.checkSecret(secret) {
+		return unauthorized()
+	}
+	return client, true
+}
+
+// verifyPKCE checks an RFC 7636 S256 code verifier
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(b64(sum[:])), []byte(challenge)) == 1
+}
+
+// userinfo returns the claims of the user the access token was issued for
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+
+	token, ok := bearer.Token(r.Header.Get("Authorization"))
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oidc"`)
+		writeError(w, http.StatusUnauthorized, "invalid_request", "missing bearer token")
+		return
+	}
+	claims, err := p.verifyAccessToken(token)
+	if err != nil {
+		invalidToken(w, err.Error())
+		return
+	}
+
+	userUUID, err := uuid.FromString(claims.Subject)
+	if err != nil {
+		invalidToken(w, "invalid subject")
+		return
+	}
+	user, err := p.users.UserByUUID(userUUID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			invalidToken(w, "the user no longer exists")
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "user lookup failed")
+		return
+	}
+	if user.Status != models.StatusActive {
+		invalidToken(w, "the user is not active")
+		return
+	}
+	writeJSON(w, http.StatusOK, ClaimsFor(user, strings.Fields(claims.Scope)))
+}
+
+func (p *Provider) verifyAccessToken(token string) (*accessTokenClaims, error) {
+	claims := &accessTokenClaims{}
+	if err := p.signer.verify(token, accessTokenType, claims); err != nil {
+		return nil, err
+	}
+	if claims.Issuer != p.issuer || claims.Audience != p.issuer+userinfoPath {
+		return nil, fmt.Errorf("%w: wrong issuer or audience", ErrInvalidJWT)
+	}
+	if p.now().Unix() >= claims.Expiry {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidJWT)
+	}
+	if !slices.Contains(strings.Fields(claims.Scope), scopeOpenID) {
+		return nil, fmt.Errorf("%w: openid scope was not granted", ErrInvalidJWT)
+	}
+	return claims, nil
+}
+
+func redirectWith(w http.ResponseWriter, r *http.Request, target string, params url.Values) {
+	u, err := url.Parse(target)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "invalid redirect target")
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q[k] = v
+		}
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func invalidToken(w http.ResponseWriter, description string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="oidc", error="invalid_token"`)
+	writeError(w, http.StatusUnauthorized, "invalid_token", description)
+}
+
+func writeError(w http.ResponseWriter, code int, errCode, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, Error{Code: errCode, Description: description})
+}
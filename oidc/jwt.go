@@ -0,0 +1,126 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidJWT = errors.New("invalid jwt")
+
+// signingAlg is the only algorithm tokens are signed and accepted with
+const signingAlg = "RS256"
+
+// JWK is a public RSA key of a JSON Web Key Set
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is the JSON Web Key Set served by the provider
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// signer signs JWTs with an RSA key identified by its RFC 7638 thumbprint
+type signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+func newSigner(key *rsa.PrivateKey) *signer {
+	return &signer{key: key, kid: thumbprint(&key.PublicKey)}
+}
+
+func (s *signer) jwk() JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: signingAlg,
+		Kid: s.kid,
+		N:   b64(s.key.N.Bytes()),
+		E:   b64(big.NewInt(int64(s.key.E)).Bytes()),
+	}
+}
+
+func (s *signer) sign(typ string, claims any) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: signingAlg, Typ: typ, Kid: s.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + b64(sig), nil
+}
+
+// verify checks the signature and typ of token and decodes its claims
+func (s *signer) verify(token, typ string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: malformed", ErrInvalidJWT)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Alg != signingAlg || header.Kid != s.kid || header.Typ != typ {
+		return fmt.Errorf("%w: unexpected header", ErrInvalidJWT)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJWT, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJWT, err)
+	}
+	return decodeSegment(parts[1], claims)
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJWT, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJWT, err)
+	}
+	return nil
+}
+
+func thumbprint(key *rsa.PublicKey) string {
+	// members in lexicographic order as required by RFC 7638
+	raw := fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`,
+		b64(big.NewInt(int64(key.E)).Bytes()), b64(key.N.Bytes()))
+	sum := sha256.Sum256([]byte(raw))
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,192 @@
+// Package oidc is an OpenID Connect provider facade over the user service.
+// It serves discovery, JWKS, the authorization code flow with PKCE, the
+// token endpoint and userinfo for first-party clients. End users are
+// authenticated with their user-service session token, the provider keeps
+// no passwords.
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultCodeTTL         = time.Minute
+	defaultTokenTTL        = time.Hour
+	defaultSessionCookie   = "session"
+	discoveryPath          = "/.well-known/openid-configuration"
+	jwksPath               = "/jwks"
+	authorizePath          = "/authorize"
+	tokenPath              = "/token"
+	userinfoPath           = "/userinfo"
+	codeChallengeS256      = "S256"
+	responseTypeCode       = "code"
+	grantAuthorizationCode = "authorization_code"
+	scopeOpenID            = "openid"
+	scopeProfile           = "profile"
+	scopeEmail             = "email"
+)
+
+// Users is the user store the provider authenticates against,
+// user.Client and its cache implement it
+type Users interface {
+	// CheckAuth returns the user of a session token
+	CheckAuth(token []byte) (*models.User, error)
+	UserByUUID(userUUID uuid.UUID) (*models.User, error)
+}
+
+// Provider is an OpenID Connect provider
+type Provider struct {
+	issuer        string
+	users         Users
+	clients       ClientStore
+	signer        *signer
+	loginURL      string
+	sessionCookie string
+	codeTTL       time.Duration
+	tokenTTL      time.Duration
+	now           func() time.Time
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+// Option configures Provider
+type Option func(p *Provider)
+
+// WithLoginURL sets where users without a session are sent, the
+// authorization request URL is passed in the return_to parameter.
+// Without it such requests fail with login_required.
+func WithLoginURL(loginURL string) Option {
+	return func(p *Provider) {
+		p.loginURL = loginURL
+	}
+}
+
+// WithSessionCookie sets the cookie holding the user-service session token
+func WithSessionCookie(name string) Option {
+	return func(p *Provider) {
+		p.sessionCookie = name
+	}
+}
+
+// WithTokenTTL sets the lifetime of access and ID tokens
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.tokenTTL = ttl
+	}
+}
+
+// WithCodeTTL sets the lifetime of authorization codes
+func WithCodeTTL(ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.codeTTL = ttl
+	}
+}
+
+// WithClock replaces time.Now
+func WithClock(now func() time.Time) Option {
+	return func(p *Provider) {
+		p.now = now
+	}
+}
+
+// New returns a provider for issuer, the https URL the handler is served at.
+// Tokens are signed with key.
+func New(issuer string, users Users, clients ClientStore, key *rsa.PrivateKey, opts ...Option) *Provider {
+	p := &Provider{
+		issuer:        strings.TrimSuffix(issuer, "/"),
+		users:         users,
+		clients:       clients,
+		signer:        newSigner(key),
+		sessionCookie: defaultSessionCookie,
+		codeTTL:       defaultCodeTTL,
+		tokenTTL:      defaultTokenTTL,
+		now:           time.Now,
+		codes:         make(map[string]authCode),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Handler serves the provider endpoints relative to the issuer path
+func (p *Provider) Handler() http.Handler {
+	base := ""
+	if u, err := url.Parse(p.issuer); err == nil {
+		base = u.Path
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+base+discoveryPath, p.discovery)
+	mux.HandleFunc("GET "+base+jwksPath, p.jwks)
+	mux.HandleFunc(base+authorizePath, p.authorize)
+	mux.HandleFunc("POST "+base+tokenPath, p.token)
+	mux.HandleFunc(base+userinfoPath, p.userinfo)
+	return mux
+}
+
+// Discovery is the OpenID provider metadata
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// Discovery returns the metadata served at /.well-known/openid-configuration
+func (p *Provider) Discovery() Discovery {
+	return Discovery{
+		Issuer:                            p.issuer,
+		AuthorizationEndpoint:             p.issuer + authorizePath,
+		TokenEndpoint:                     p.issuer + tokenPath,
+		UserinfoEndpoint:                  p.issuer + userinfoPath,
+		JWKSURI:                           p.issuer + jwksPath,
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{grantAuthorizationCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlg},
+		ScopesSupported:                   []string{scopeOpenID, scopeProfile, scopeEmail},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeS256},
+		ClaimsSupported: []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"name", "given_name", "family_name", "preferred_username", "picture", "email"},
+	}
+}
+
+// JWKS returns the public keys tokens are signed with
+func (p *Provider) JWKS() JWKS {
+	return JWKS{Keys: []JWK{p.signer.jwk()}}
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, p.Discovery())
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, p.JWKS())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
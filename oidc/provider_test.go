@@ -0,0 +1,260 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testSession  = "session-token"
+	testRedirect = "https://app.example.com/callback"
+)
+
+// fakeUsers knows one active user signed in with testSession
+type fakeUsers struct {
+	user *models.User
+}
+
+func (u fakeUsers) CheckAuth(token []byte) (*models.User, error) {
+	if string(token) != testSession {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return u.user, nil
+}
+
+func (u fakeUsers) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
+	if userUUID != u.user.UserUUID {
+		return nil, models.ErrUserNotFound
+	}
+	return u.user, nil
+}
+
+type testProvider struct {
+	*Provider
+	srv    *httptest.Server
+	client *Client
+	http   *http.Client
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	users := fakeUsers{user: &models.User{
+		UserUUID:  uuid.Must(uuid.NewV4()),
+		Email:     "jane@example.com",
+		Username:  "jane",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Status:    models.StatusActive,
+	}}
+	clients := NewMemoryClients()
+	client, err := clients.Register("app", []string{testRedirect}, false)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tp := &testProvider{client: client}
+	var handler http.Handler
+	tp.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(tp.srv.Close)
+	tp.Provider = New(tp.srv.URL, users, clients, key)
+	handler = tp.Handler()
+
+	tp.http = tp.srv.Client()
+	tp.http.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return tp
+}
+
+// pkce returns a code verifier and its S256 challenge
+func pkce(t *testing.T) (string, string) {
+	t.Helper()
+	verifier, err := randomString(48)
+	if err != nil {
+		t.Fatalf("verifier: %v", err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, b64(sum[:])
+}
+
+// authorize runs the authorization request of the signed in user and returns the code
+func (tp *testProvider) authorize(t *testing.T, challenge string) string {
+	t.Helper()
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {tp.client.ID},
+		"redirect_uri":          {testRedirect},
+		"scope":                 {"openid profile email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, tp.srv.URL+authorizePath+"?"+q.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer "+testSession)
+	resp, err := tp.http.Do(req)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("redirect location: %v", err)
+	}
+	if got := loc.Scheme + "://" + loc.Host + loc.Path; got != testRedirect {
+		t.Fatalf("redirected to %s, want %s", got, testRedirect)
+	}
+	if loc.Query().Get("state") != "xyz" {
+		t.Fatalf("state = %q, want xyz", loc.Query().Get("state"))
+	}
+	code := loc.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in %s", loc)
+	}
+	return code
+}
+
+// exchange posts the code to the token endpoint and decodes the answer into out
+func (tp *testProvider) exchange(t *testing.T, code, verifier string, out any) int {
+	t.Helper()
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {testRedirect},
+		"code_verifier": {verifier},
+	}
+	req, _ := http.NewRequest(http.MethodPost, tp.srv.URL+tokenPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(tp.client.ID, tp.client.Secret)
+	resp, err := tp.http.Do(req)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	tp := newTestProvider(t)
+
+	resp, err := tp.http.Get(tp.srv.URL + discoveryPath)
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	var disc Discovery
+	if err := json.NewDecoder(resp.Body).Decode(&disc); err != nil {
+		t.Fatalf("decode discovery: %v", err)
+	}
+	resp.Body.Close()
+	if disc.Issuer != tp.srv.URL || disc.TokenEndpoint != tp.srv.URL+tokenPath {
+		t.Fatalf("discovery = %+v", disc)
+	}
+
+	verifier, challenge := pkce(t)
+	code := tp.authorize(t, challenge)
+
+	var tokens TokenResponse
+	if status := tp.exchange(t, code, verifier, &tokens); status != http.StatusOK {
+		t.Fatalf("token status = %d", status)
+	}
+	var id idTokenClaims
+	if err := tp.signer.verify(tokens.IDToken, idTokenType, &id); err != nil {
+		t.Fatalf("verify id token: %v", err)
+	}
+	if id.Issuer != tp.srv.URL || id.Audience != tp.client.ID || id.Nonce != "n-0S6_WzA2Mj" {
+		t.Fatalf("id token claims = %+v", id)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, disc.UserinfoEndpoint, nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	resp, err = tp.http.Do(req)
+	if err != nil {
+		t.Fatalf("userinfo: %v", err)
+	}
+	defer resp.Body.Close()
+	var claims Claims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		t.Fatalf("decode userinfo: %v", err)
+	}
+	if claims.Subject != id.Subject || claims.Email != "jane@example.com" || claims.Name != "Jane Doe" {
+		t.Fatalf("userinfo = %+v", claims)
+	}
+}
+
+func TestCodeIsRedeemedOnce(t *testing.T) {
+	tp := newTestProvider(t)
+	verifier, challenge := pkce(t)
+	code := tp.authorize(t, challenge)
+
+	var tokens TokenResponse
+	if status := tp.exchange(t, code, verifier, &tokens); status != http.StatusOK {
+		t.Fatalf("first exchange status = %d", status)
+	}
+	var e Error
+	if status := tp.exchange(t, code, verifier, &e); status != http.StatusBadRequest || e.Code != "invalid_grant" {
+		t.Fatalf("reused code: status %d, error %+v, want invalid_grant", status, e)
+	}
+}
+
+func TestWrongCodeVerifier(t *testing.T) {
+	tp := newTestProvider(t)
+	verifier, challenge := pkce(t)
+	code := tp.authorize(t, challenge)
+
+	other, _ := pkce(t)
+	var e Error
+	if status := tp.exchange(t, code, other, &e); status != http.StatusBadRequest || e.Code != "invalid_grant" {
+		t.Fatalf("wrong verifier: status %d, error %+v, want invalid_grant", status, e)
+	}
+	// the code is spent by the failed attempt
+	if status := tp.exchange(t, code, verifier, &e); status != http.StatusBadRequest {
+		t.Fatalf("code accepted after a failed attempt, status %d", status)
+	}
+}
+
+func TestRegisterRedirectURIs(t *testing.T) {
+	for uri, ok := range map[string]bool{
+		"https://app.example.com/callback": true,
+		"http://127.0.0.1:8080/callback":   true,
+		"http://[::1]/callback":            true,
+		"http://localhost:3000/callback":   true,
+		"http://app.example.com/callback":  false,
+		"https://app.example.com/cb#frag":  false,
+		"myapp:/callback":                  false,
+		"/callback":                        false,
+	} {
+		_, err := NewMemoryClients().Register("app", []string{uri}, true)
+		if ok && err != nil {
+			t.Errorf("Register(%q): %v", uri, err)
+		}
+		if !ok && !errors.Is(err, ErrInvalidRedirectURI) {
+			t.Errorf("Register(%q) error = %v, want ErrInvalidRedirectURI", uri, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/policy"
+)
+
+// Option configures UsersAPI in New
+type Option func(api *UsersAPI)
+
+// WithPasswordPolicy sets the password policy checked before SignUp,
+// nil disables the client-side check
+func WithPasswordPolicy(p *policy.Policy) Option {
+	return func(api *UsersAPI) {
+		api.passwordPolicy = p
+	}
+}
+
+// WithTimeout sets the deadline of every call, 60 seconds by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(api *UsersAPI) {
+		api.timeout = timeout
+	}
+}
+
+// WithBatchWorkers sets how many UsersByUUIDs chunks are requested
+// concurrently, 4 by default. Values below 1 mean 1.
+func WithBatchWorkers(n int) Option {
+	return func(api *UsersAPI) {
+		api.batchWorkers = max(n, 1)
+	}
+}
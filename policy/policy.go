@@ -0,0 +1,159 @@
+// Package policy holds the password rules shared by the user service
+// and its clients. The client checks a password before SignUp sends it,
+// the server runs the same Check and returns the violations as a
+// models.ValidationError.
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/garden-raccoon/user-pkg/models"
+)
+
+// PasswordField is the field name used in violations
+const PasswordField = "password"
+
+//go:embed common-passwords.txt
+var commonPasswordsList []byte
+
+var (
+	commonOnce      sync.Once
+	commonPasswords map[string]struct{}
+)
+
+// Policy is a configurable set of password rules
+type Policy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// MaxLength is the maximum size in bytes, 0 means unlimited
+	MaxLength int
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// RejectIdentity rejects passwords containing the email or username
+	RejectIdentity bool
+	// RejectCommon rejects passwords from the bundled common-password list
+	RejectCommon bool
+}
+
+// Identity is the account data a password must not contain
+type Identity struct {
+	Email    string
+	Username string
+}
+
+// Default returns the policy used by the user service
+func Default() *Policy {
+	return &Policy{
+		MinLength:      8,
+		MaxLength:      72, // bcrypt ignores everything after 72 bytes
+		RequireLower:   true,
+		RequireDigit:   true,
+		RejectIdentity: true,
+		RejectCommon:   true,
+	}
+}
+
+// Check returns a *models.ValidationError listing every rule the password breaks
+func (p *Policy) Check(password []byte, id Identity) error {
+	return p.Violations(password, id).Err()
+}
+
+// Violations is Check returning the collected violations, which may be empty
+func (p *Policy) Violations(password []byte, id Identity) *models.ValidationError {
+	errs := &models.ValidationError{}
+	if p == nil {
+		return errs
+	}
+
+	if !utf8.Valid(password) {
+		errs.Add(PasswordField, "must be valid UTF-8")
+		return errs
+	}
+
+	if n := utf8.RuneCount(password); n < p.MinLength {
+		errs.Add(PasswordField, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		errs.Add(PasswordField, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range string(password) {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		errs.Add(PasswordField, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		errs.Add(PasswordField, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		errs.Add(PasswordField, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		errs.Add(PasswordField, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(string(password))
+	if p.RejectIdentity && containsIdentity(lowered, id) {
+		errs.Add(PasswordField, "must not contain the email or username")
+	}
+	if p.RejectCommon && IsCommon(password) {
+		errs.Add(PasswordField, "is too common")
+	}
+	return errs
+}
+
+// IsCommon reports whether password is in the bundled common-password list
+func IsCommon(password []byte) bool {
+	commonOnce.Do(loadCommonPasswords)
+	_, ok := commonPasswords[strings.ToLower(string(password))]
+	return ok
+}
+
+func loadCommonPasswords() {
+	commonPasswords = make(map[string]struct{})
+	sc := bufio.NewScanner(bytes.NewReader(commonPasswordsList))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			commonPasswords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+}
+
+// minIdentityLen keeps short local parts like "al" from rejecting half of all passwords
+const minIdentityLen = 3
+
+func containsIdentity(password string, id Identity) bool {
+	parts := []string{id.Username, id.Email}
+	if local, _, ok := strings.Cut(id.Email, "@"); ok {
+		parts = append(parts, local)
+	}
+	for _, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len(part) >= minIdentityLen && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
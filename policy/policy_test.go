@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/garden-raccoon/user-pkg/models"
+)
+
+func TestViolations(t *testing.T) {
+	jane := Identity{Email: "jane.doe@example.com", Username: "janed"}
+	tests := []struct {
+		name     string
+		policy   *Policy
+		password string
+		id       Identity
+		want     []string
+	}{
+		{"nil policy", nil, "", jane, nil},
+		{"default accepts", Default(), "correct horse 9", jane, nil},
+		{"too short", &Policy{MinLength: 8}, "abc1", Identity{}, []string{"must be at least 8 characters long"}},
+		// length is counted in characters, "ééééé" is 10 bytes
+		{"characters not bytes", &Policy{MinLength: 6}, "ééééé", Identity{}, []string{"must be at least 6 characters long"}},
+		{"too long", &Policy{MaxLength: 4}, "ééé", Identity{}, []string{"must be at most 4 bytes long"}},
+		{"invalid utf-8", Default(), "abc\xffdefg1", Identity{}, []string{"must be valid UTF-8"}},
+		{
+			"character classes", &Policy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true},
+			"", Identity{},
+			[]string{"must contain an uppercase letter", "must contain a lowercase letter", "must contain a digit", "must contain a symbol"},
+		},
+		{"non-ascii letters", &Policy{RequireUpper: true, RequireLower: true}, "ÄÖÜßéñ", Identity{}, nil},
+		{"non-ascii digit and symbol", &Policy{RequireDigit: true, RequireSymbol: true}, "٣€", Identity{}, nil},
+		{"username", &Policy{RejectIdentity: true}, "xxJaneDxx", jane, []string{"must not contain the email or username"}},
+		{"email", &Policy{RejectIdentity: true}, "jane.doe@example.com!", jane, []string{"must not contain the email or username"}},
+		{"email local part", &Policy{RejectIdentity: true}, "1JANE.DOE1", jane, []string{"must not contain the email or username"}},
+		{"short identity ignored", &Policy{RejectIdentity: true}, "alpine", Identity{Email: "al@example.com"}, nil},
+		{"identity not checked", &Policy{}, "janed", jane, nil},
+		{"common", &Policy{RejectCommon: true}, "QWERTY123", Identity{}, []string{"is too common"}},
+		{
+			"every rule", Default(), "password", Identity{Username: "password"},
+			[]string{"must contain a digit", "must not contain the email or username", "is too common"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.policy.Violations([]byte(tt.password), tt.id)
+			var got []string
+			for _, v := range errs.Violations {
+				if v.Field != PasswordField {
+					t.Fatalf("violation of field %q, want %q", v.Field, PasswordField)
+				}
+				got = append(got, v.Description)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("violations = %q, want %q", got, tt.want)
+			}
+
+			err := tt.policy.Check([]byte(tt.password), tt.id)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check: %v", err)
+				}
+				return
+			}
+			var ve *models.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Check error = %v, want a *models.ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestIsCommon(t *testing.T) {
+	for _, password := range []string{"123456", "password", "Password", "qwertyuiop"} {
+		if !IsCommon([]byte(password)) {
+			t.Errorf("IsCommon(%q) = false", password)
+		}
+	}
+	for _, password := range []string{"", "correct horse 9", "password "} {
+		if IsCommon([]byte(password)) {
+			t.Errorf("IsCommon(%q) = true", password)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "github.com/garden-raccoon/user-pkg"
+
+// WithTracerProvider enables a client span per RPC and trace context propagation
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(api *UsersAPI) {
+		api.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider enables request count, error count and latency metrics per RPC
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(api *UsersAPI) {
+		api.meterProvider = mp
+	}
+}
+
+// WithPropagator sets how trace context is written to gRPC metadata,
+// W3C trace context by default
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(api *UsersAPI) {
+		api.propagator = p
+	}
+}
+
+// telemetry instruments RPCs with the providers given in options, never the otel globals
+type telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requests metric.Int64Counter
+	failures metric.Int64Counter
+	latency  metric.Float64Histogram
+}
+
+func newTelemetry(api *UsersAPI) (*telemetry, error) {
+	t := &telemetry{propagator: api.propagator}
+	if api.tracerProvider != nil {
+		t.tracer = api.tracerProvider.Tracer(instrumentationName)
+		if t.propagator == nil {
+			t.propagator = propagation.TraceContext{}
+		}
+	}
+	if api.meterProvider == nil {
+		return t, nil
+	}
+
+	meter := api.meterProvider.Meter(instrumentationName)
+	var err error
+	if t.requests, err = meter.Int64Counter("rpc.client.requests",
+		metric.WithDescription("Number of user service RPCs")); err != nil {
+		return nil, err
+	}
+	if t.failures, err = meter.Int64Counter("rpc.client.errors",
+		metric.WithDescription("Number of failed user service RPCs")); err != nil {
+		return nil, err
+	}
+	if t.latency, err = meter.Float64Histogram("rpc.client.duration",
+		metric.WithDescription("Latency of user service RPCs"), metric.WithUnit("ms")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *telemetry) unaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, span := t.start(ctx, method)
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	t.finish(ctx, span, method, start, err)
+	return err
+}
+
+func (t *telemetry) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, span := t.start(ctx, method)
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		t.finish(ctx, span, method, start, err)
+		return nil, err
+	}
+	ts := &tracedStream{ClientStream: stream, serverStreams: desc.ServerStreams, done: make(chan struct{})}
+	ts.finish = func(err error) {
+		ts.once.Do(func() {
+			close(ts.done)
+			t.finish(ctx, span, method, start, err)
+		})
+	}
+	// a stream the caller stops reading ends with its context
+	go func() {
+		select {
+		case <-ts.done:
+		case <-ctx.Done():
+			ts.finish(status.FromContextError(ctx.Err()).Err())
+		}
+	}()
+	return ts, nil
+}
+
+func (t *telemetry) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	if t.tracer == nil {
+		return ctx, nil
+	}
+	service, name := splitMethod(method)
+	ctx, span := t.tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+		))
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	t.propagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func (t *telemetry) finish(ctx context.Context, span trace.Span, method string, start time.Time, err error) {
+	code := status.Code(err)
+	if span != nil {
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		span.End()
+	}
+
+	if t.requests == nil {
+		return
+	}
+	service, name := splitMethod(method)
+	attrs := metric.WithAttributes(
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", name),
+		attribute.String("rpc.grpc.status_code", code.String()),
+	)
+	t.requests.Add(ctx, 1, attrs)
+	if err != nil {
+		t.failures.Add(ctx, 1, attrs)
+	}
+	t.latency.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs)
+}
+
+// tracedStream ends the span once, when the stream is finished or its
+// context is done. For client streams that is when the single response is received.
+type tracedStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	done          chan struct{}
+	finish        func(err error)
+}
+
+func (s *tracedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil && !s.serverStreams, errors.Is(err, io.EOF):
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	}
+	return err
+}
+
+// splitMethod splits "/package.Service/Method"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", service
+	}
+	return service, method
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,197 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// exportServer streams users until the client goes away
+type exportServer struct {
+	testServer
+}
+
+func (s *exportServer) ExportUsers(_ *proto.ListUsersRequest, stream grpc.ServerStreamingServer[proto.User]) error {
+	for {
+		if err := stream.Send(&proto.User{UserUuid: uuid.Must(uuid.NewV4()).Bytes()}); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+type telemetryFixture struct {
+	api    Client
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+}
+
+func newTelemetryFixture(t *testing.T, srv proto.UserServiceServer) *telemetryFixture {
+	t.Helper()
+	f := &telemetryFixture{spans: tracetest.NewInMemoryExporter(), reader: sdkmetric.NewManualReader()}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr,
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(f.spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(f.reader))),
+		WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { api.Close() })
+	f.api = api
+	return f
+}
+
+// span waits for the ended span of the method
+func (f *telemetryFixture) span(t *testing.T, name string) tracetest.SpanStub {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, s := range f.spans.GetSpans() {
+			if s.Name == name {
+				return s
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no span %s in %v", name, f.spans.GetSpans())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// count is the value of the int64 counter for the method, or the number of
+// histogram records
+func (f *telemetryFixture) count(t *testing.T, metric, method string) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := f.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var n int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != metric {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					if v, _ := dp.Attributes.Value("rpc.method"); v.AsString() == method {
+						n += dp.Value
+					}
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					if v, _ := dp.Attributes.Value("rpc.method"); v.AsString() == method {
+						n += int64(dp.Count)
+					}
+				}
+			}
+		}
+	}
+	return n
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTelemetryUnary(t *testing.T) {
+	var traceparent []string
+	unknown := uuid.Must(uuid.NewV4())
+	srv := &testServer{}
+	srv.userBy = func(ctx context.Context, req *proto.UserGetter) (*proto.User, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		traceparent = md.Get("traceparent")
+		if uuid.FromBytesOrNil(req.GetUserUuid()) == unknown {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return &proto.User{UserUuid: req.GetUserUuid()}, nil
+	}
+	f := newTelemetryFixture(t, srv)
+
+	if _, err := f.api.UserByUUID(uuid.Must(uuid.NewV4())); err != nil {
+		t.Fatalf("UserByUUID: %v", err)
+	}
+	span := f.span(t, "service.UserService/UserBy")
+	if span.SpanKind != trace.SpanKindClient || span.Status.Code == otelcodes.Error {
+		t.Fatalf("span = %+v", span)
+	}
+	if got := attr(span, "rpc.grpc.status_code"); got != "OK" {
+		t.Fatalf("status_code = %q, want OK", got)
+	}
+	if attr(span, "rpc.service") != "service.UserService" || attr(span, "rpc.method") != "UserBy" {
+		t.Fatalf("span attributes = %v", span.Attributes)
+	}
+	if len(traceparent) != 1 || traceparent[0][3:35] != span.SpanContext.TraceID().String() {
+		t.Fatalf("traceparent = %v, want trace %s", traceparent, span.SpanContext.TraceID())
+	}
+
+	f.spans.Reset()
+	if _, err := f.api.UserByUUID(unknown); err == nil {
+		t.Fatal("UserByUUID of an unknown user succeeded")
+	}
+	span = f.span(t, "service.UserService/UserBy")
+	if span.Status.Code != otelcodes.Error || attr(span, "rpc.grpc.status_code") != "NotFound" {
+		t.Fatalf("failed span status = %+v, attributes %v", span.Status, span.Attributes)
+	}
+
+	if n := f.count(t, "rpc.client.requests", "UserBy"); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+	if n := f.count(t, "rpc.client.errors", "UserBy"); n != 1 {
+		t.Fatalf("errors = %d, want 1", n)
+	}
+	if n := f.count(t, "rpc.client.duration", "UserBy"); n != 2 {
+		t.Fatalf("duration records = %d, want 2", n)
+	}
+}
+
+// TestTelemetryAbandonedStream checks a stream the caller stops reading
+// still ends its span and records its metrics
+func TestTelemetryAbandonedStream(t *testing.T) {
+	f := newTelemetryFixture(t, &exportServer{})
+
+	for _, err := range f.api.ExportUsers(models.ListUsersFilter{}) {
+		if err != nil {
+			t.Fatalf("ExportUsers: %v", err)
+		}
+		break
+	}
+
+	span := f.span(t, "service.UserService/ExportUsers")
+	if got := attr(span, "rpc.grpc.status_code"); got != "Canceled" {
+		t.Fatalf("status_code = %q, want Canceled", got)
+	}
+	if n := f.count(t, "rpc.client.requests", "ExportUsers"); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+	if n := f.count(t, "rpc.client.duration", "ExportUsers"); n != 1 {
+		t.Fatalf("duration records = %d, want 1", n)
+	}
+}
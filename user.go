@@ -2,23 +2,35 @@ package user
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"iter"
+	"time"
+
 	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/garden-raccoon/user-pkg/policy"
 	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
-	"sync"
-	"time"
 
 	proto "github.com/garden-raccoon/user-pkg/protocols/user"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const timeOut = 60
 
+// IUserAPI is kept as it was so existing implementations and mocks still
+// satisfy it. The other capabilities of UsersAPI are the interfaces below,
+// Client combines all of them.
 type IUserAPI interface {
 	// SignUp is
 	SignUp(email string, password []byte, userType int) ([]byte, error)
@@ -34,26 +46,208 @@ type IUserAPI interface {
 	// SignIn is
 	SignIn(email string, password []byte) ([]byte, error)
 
+	// HealthCheck returns nil when the service is serving
 	HealthCheck() error
 
 	// Close GRPC Api connection
 	Close() error
 }
 
+// DirectoryAPI creates and looks up users
+type DirectoryAPI interface {
+	// CreateUser is
+	CreateUser(user *models.User) error
+
+	// UserByEmail is
+	UserByEmail(email string) (*models.User, error)
+
+	// UsersByUUIDs returns the found users and the uuids that were not found
+	UsersByUUIDs(userUUIDs []uuid.UUID) (*models.UsersBatch, error)
+
+	// ListUsers returns one page of users, use AllUsers to walk all pages
+	ListUsers(filter models.ListUsersFilter, cursor string) (*models.UsersPage, error)
+}
+
+// AccountAPI changes the lifecycle state of users
+type AccountAPI interface {
+	// DeactivateUser blocks sign in until ReactivateUser is called
+	DeactivateUser(userUUID uuid.UUID, reason string) (*models.User, error)
+
+	// ReactivateUser is
+	ReactivateUser(userUUID uuid.UUID, reason string) (*models.User, error)
+
+	// DeleteUser soft deletes the user, it is purged after gracePeriod,
+	// zero gracePeriod means the server default, purge deletes it at once
+	DeleteUser(userUUID uuid.UUID, gracePeriod time.Duration, purge bool) (*models.User, error)
+
+	// ExportUserData returns everything stored about the user
+	ExportUserData(userUUID uuid.UUID) (*models.UserDataExport, error)
+
+	// EraseUser anonymizes the user PII, the uuid stays valid
+	EraseUser(userUUID uuid.UUID, reason string) (*models.User, error)
+}
+
+// WatchAPI streams user changes
+type WatchAPI interface {
+	// Watch streams user events after fromCursor until ctx is done
+	Watch(ctx context.Context, fromCursor uint64) *UserWatch
+}
+
+// BulkAPI imports and exports users in bulk
+type BulkAPI interface {
+	// ImportUsers streams the records to the server, users whose email exists are skipped.
+	// Invalid records are reported in the result and not sent.
+	ImportUsers(records iter.Seq2[*models.ImportRecord, error], dryRun bool) (*models.ImportResult, error)
+
+	// ExportUsers streams every user matching the filter
+	ExportUsers(filter models.ListUsersFilter) iter.Seq2[*models.User, error]
+}
+
+// WebhookAPI manages webhooks receiving user events
+type WebhookAPI interface {
+	// RegisterWebhook returns the webhook with its signing secret, keep it, it is not returned again
+	RegisterWebhook(url string, eventTypes []models.UserEventType) (*models.Webhook, error)
+
+	// UnregisterWebhook is
+	UnregisterWebhook(webhookUUID uuid.UUID) error
+
+	// ListDeadLetters returns the deliveries of the webhook that ran out of retries
+	ListDeadLetters(webhookUUID uuid.UUID) ([]*models.WebhookDelivery, error)
+}
+
+// AuditAPI reads the audit log
+type AuditAPI interface {
+	// ListAuditEvents returns one page of audit events, use AllAuditEvents to walk all pages
+	ListAuditEvents(filter models.AuditFilter, cursor string) (*models.AuditPage, error)
+}
+
+// ServiceClientAPI manages machine clients and their tokens
+type ServiceClientAPI interface {
+	// RegisterServiceClient returns the client with its secret, keep it, it is not returned again
+	RegisterServiceClient(name string, scopes []string) (*models.ServiceClient, error)
+
+	// DeleteServiceClient is
+	DeleteServiceClient(clientID string) error
+
+	// ClientCredentialsToken issues a short-lived service token, use WithClientCredentials to keep one fresh
+	ClientCredentialsToken(clientID string, secret []byte, scopes []string) (*models.ServiceToken, error)
+}
+
+// IdentityAPI signs in with and links external identity providers
+type IdentityAPI interface {
+	// SignInExternal returns a session token for the user linked to the subject of a verified external ID token
+	SignInExternal(provider, idToken, nonce string) ([]byte, error)
+
+	// LinkIdentity links the subject of the external ID token to the user
+	LinkIdentity(userUUID uuid.UUID, provider, idToken, nonce string) (*models.ExternalIdentity, error)
+
+	// UnlinkIdentity is
+	UnlinkIdentity(userUUID uuid.UUID, provider string) error
+
+	// ListIdentities returns the external identities linked to the user
+	ListIdentities(userUUID uuid.UUID) ([]*models.ExternalIdentity, error)
+}
+
+// HealthAPI reports the serving state kept by WithHealthWatch
+type HealthAPI interface {
+	// Probe asks the server even when a health watch is running
+	Probe() error
+
+	// Ready reports the cached serving state kept by WithHealthWatch
+	Ready() bool
+
+	// HealthChanges receives the serving state kept by WithHealthWatch on every change
+	// until the returned func is called
+	HealthChanges() (<-chan HealthStatus, func())
+}
+
+// Client is every capability of the user service client
+type Client interface {
+	IUserAPI
+	DirectoryAPI
+	AccountAPI
+	WatchAPI
+	BulkAPI
+	WebhookAPI
+	AuditAPI
+	ServiceClientAPI
+	IdentityAPI
+	HealthAPI
+
+	// WithContext returns a client making its calls with ctx as parent
+	WithContext(ctx context.Context) Client
+}
+
 // UsersAPI is profile-service GRPC UsersAPI
 // structure with client Connection
 type UsersAPI struct {
-	addr    string
-	timeout time.Duration
-	mu      sync.Mutex
+	addr           string
+	timeout        time.Duration
+	batchWorkers   int
+	passwordPolicy *policy.Policy
+	health         *healthWatcher
+	breakers       *breakers
+	limits         map[string]LimitConfig
+
+	// ctx is the parent of every call, set by WithContext
+	ctx                  context.Context
+	perRPCCredentials    []credentials.PerRPCCredentials
+	transportCredentials credentials.TransportCredentials
+	allowInsecure        bool
+
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+
+	endpoints     []string
+	resolvers     []resolver.Builder
+	serviceConfig string
+
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
 	*grpc.ClientConn
 	proto.UserServiceClient
 	grpc_health_v1.HealthClient
 }
 
 // New create new Users IEmployerAPI instance
-func New(addr string) (IUserAPI, error) {
-	api := &UsersAPI{timeout: timeOut * time.Second}
+func New(addr string, opts ...Option) (IUserAPI, error) {
+	return NewClient(addr, opts...)
+}
+
+// NewClient is New returning every capability of the client
+func NewClient(addr string, opts ...Option) (Client, error) {
+	api := &UsersAPI{
+		timeout:        timeOut * time.Second,
+		batchWorkers:   defaultBatchWorkers,
+		passwordPolicy: policy.Default(),
+	}
+	for _, opt := range opts {
+		opt(api)
+	}
+
+	api.unaryInterceptors = append(api.unaryInterceptors, requestIDUnaryInterceptor)
+	api.streamInterceptors = append(api.streamInterceptors, requestIDStreamInterceptor)
+	if api.tracerProvider != nil || api.meterProvider != nil {
+		t, err := newTelemetry(api)
+		if err != nil {
+			return nil, fmt.Errorf("create Users UsersAPI telemetry: %w", err)
+		}
+		api.unaryInterceptors = append(api.unaryInterceptors, t.unaryInterceptor)
+		api.streamInterceptors = append(api.streamInterceptors, t.streamInterceptor)
+	}
+	if api.breakers != nil {
+		api.unaryInterceptors = append(api.unaryInterceptors, api.breakers.unaryInterceptor)
+		api.streamInterceptors = append(api.streamInterceptors, api.breakers.streamInterceptor)
+	}
+	if len(api.limits) > 0 {
+		l, err := newLimiters(api.limits, api.meterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("create Users UsersAPI limits: %w", err)
+		}
+		api.unaryInterceptors = append(api.unaryInterceptors, l.unaryInterceptor)
+		api.streamInterceptors = append(api.streamInterceptors, l.streamInterceptor)
+	}
 
 	if err := api.initConn(addr); err != nil {
 		return nil, fmt.Errorf("create Users UsersAPI:  %w", err)
@@ -61,21 +255,50 @@ func New(addr string) (IUserAPI, error) {
 	api.HealthClient = grpc_health_v1.NewHealthClient(api.ClientConn)
 
 	api.UserServiceClient = proto.NewUserServiceClient(api.ClientConn)
+	if api.health != nil {
+		api.health.start(api.HealthClient)
+	}
 	return api, nil
 }
 
+// Close stops the health watch and closes the connection
+func (api *UsersAPI) Close() error {
+	if api.health != nil {
+		api.health.stop()
+	}
+	return api.ClientConn.Close()
+}
+
 func (api *UsersAPI) UpdateUser(user *models.UpdateUserRequest) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	if err := user.Validate(); err != nil {
+		return nil, fmt.Errorf("updateUser: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 	protoUser := models.Proto(*user)
 	resp, err := api.UserServiceClient.UpdateUser(ctx, protoUser)
 	if err != nil {
 		return nil, fmt.Errorf("updateUser api request: %w", err)
 	}
-	fmt.Printf("resp is %s \n", resp)
 	return models.UserFromProto(resp), nil
 }
 
+// CreateUser is
+func (api *UsersAPI) CreateUser(user *models.User) error {
+	if err := user.Validate(); err != nil {
+		return fmt.Errorf("createUser: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	if _, err := api.UserServiceClient.CreateUser(ctx, user.Proto()); err != nil {
+		return fmt.Errorf("createUser api request: %w", err)
+	}
+	return nil
+}
+
 // initConn initialize connection to Grpc servers
 func (api *UsersAPI) initConn(addr string) (err error) {
 	var kacp = keepalive.ClientParameters{
@@ -84,28 +307,70 @@ func (api *UsersAPI) initConn(addr string) (err error) {
 		PermitWithoutStream: true,             // send pings even without active streams
 	}
 
-	api.ClientConn, err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(kacp))
+	// a single address is dialed as before, balancing and health checking
+	// only apply to several endpoints, DNS or a custom resolver
+	target := api.target(addr)
+	serviceConfig := api.serviceConfig
+	if serviceConfig == "" && api.balanced(target) {
+		serviceConfig = defaultServiceConfig
+	}
+
+	transportCredentials := api.transportCredentials
+	if transportCredentials == nil {
+		transportCredentials = insecure.NewCredentials()
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithKeepaliveParams(kacp),
+		grpc.WithResolvers(api.resolvers...),
+		grpc.WithChainUnaryInterceptor(api.unaryInterceptors...),
+		grpc.WithChainStreamInterceptor(api.streamInterceptors...),
+	}
+	if serviceConfig != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(serviceConfig))
+	}
+	for _, creds := range api.perRPCCredentials {
+		if api.allowInsecure {
+			creds = insecureCredentials{creds}
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(creds))
+	}
+
+	api.ClientConn, err = grpc.NewClient(target, dialOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to dial: %w", err)
 	}
 	return
 }
 func (api *UsersAPI) CheckAuth(token []byte) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 
 	protoToken := &proto.TokenRequest{Token: token}
 	resp, err := api.UserServiceClient.CheckAuth(ctx, protoToken)
 	if err != nil {
+		if se, ok := models.AccountStatusErrorFromError(err); ok {
+			return nil, fmt.Errorf("checkAuth: %w", se)
+		}
 		return nil, fmt.Errorf("checkAuth api request: %w", err)
 	}
 
-	return models.UserFromProto(resp), nil
+	user := models.UserFromProto(resp)
+	if user.Status != models.StatusActive {
+		return nil, fmt.Errorf("checkAuth: %w", &models.AccountStatusError{Status: user.Status})
+	}
+	return user, nil
 }
 
 // SignUp is
 func (api *UsersAPI) SignUp(email string, password []byte, userType int) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	errs := models.EmailViolations(email)
+	errs.Merge(api.passwordPolicy.Violations(password, policy.Identity{Email: email}))
+	if err := errs.Err(); err != nil {
+		return nil, fmt.Errorf("signUp: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 
 	opts := &proto.SignUpRequest{
@@ -113,7 +378,6 @@ func (api *UsersAPI) SignUp(email string, password []byte, userType int) ([]byte
 		Password: password,
 		UserType: int64(userType),
 	}
-	fmt.Printf("epts email is %s\n", opts.Email)
 	resp, err := api.UserServiceClient.SignUp(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("signUp api request has been failed: %w", err)
@@ -123,7 +387,7 @@ func (api *UsersAPI) SignUp(email string, password []byte, userType int) ([]byte
 
 // SignIn is
 func (api *UsersAPI) SignIn(email string, password []byte) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 
 	opts := &proto.SignInRequest{
@@ -133,31 +397,284 @@ func (api *UsersAPI) SignIn(email string, password []byte) ([]byte, error) {
 
 	resp, err := api.UserServiceClient.SignIn(ctx, opts)
 	if err != nil {
+		if se, ok := models.AccountStatusErrorFromError(err); ok {
+			return nil, fmt.Errorf("signIn: %w", se)
+		}
 		return nil, fmt.Errorf("signIn api request: %w", err)
 	}
 
 	return resp.Token, nil
 }
 
-func (api *UsersAPI) HealthCheck() error {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+// DeactivateUser is
+func (api *UsersAPI) DeactivateUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.UserStatusRequest{UserUuid: userUUID.Bytes(), Reason: reason}
+	resp, err := api.UserServiceClient.DeactivateUser(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("deactivateUser api request: %w", err)
+	}
+	return models.UserFromProto(resp), nil
+}
+
+// ReactivateUser is
+func (api *UsersAPI) ReactivateUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 
-	api.mu.Lock()
-	defer api.mu.Unlock()
+	opts := &proto.UserStatusRequest{UserUuid: userUUID.Bytes(), Reason: reason}
+	resp, err := api.UserServiceClient.ReactivateUser(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("reactivateUser api request: %w", err)
+	}
+	return models.UserFromProto(resp), nil
+}
+
+// DeleteUser is
+func (api *UsersAPI) DeleteUser(userUUID uuid.UUID, gracePeriod time.Duration, purge bool) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
 
-	resp, err := api.HealthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "userapi"})
+	opts := &proto.DeleteUserRequest{UserUuid: userUUID.Bytes(), Purge: purge}
+	if gracePeriod > 0 {
+		opts.GracePeriod = durationpb.New(gracePeriod)
+	}
+	resp, err := api.UserServiceClient.DeleteUser(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("healthcheck error: %w", err)
+		return nil, fmt.Errorf("deleteUser api request: %w", err)
 	}
+	return models.UserFromProto(resp), nil
+}
+
+// ExportUserData is
+func (api *UsersAPI) ExportUserData(userUUID uuid.UUID) (*models.UserDataExport, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
 
-	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
-		return fmt.Errorf("node is %s", errors.New("service is unhealthy"))
+	resp, err := api.UserServiceClient.ExportUserData(ctx, &proto.UserRequest{UserUuid: userUUID.Bytes()})
+	if err != nil {
+		return nil, fmt.Errorf("exportUserData api request: %w", err)
+	}
+	return models.UserDataExportFromProto(resp), nil
+}
+
+// EraseUser is
+func (api *UsersAPI) EraseUser(userUUID uuid.UUID, reason string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.EraseUserRequest{UserUuid: userUUID.Bytes(), Reason: reason}
+	resp, err := api.UserServiceClient.EraseUser(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("eraseUser api request: %w", err)
+	}
+	return models.UserFromProto(resp), nil
+}
+
+// ListUsers is
+func (api *UsersAPI) ListUsers(filter models.ListUsersFilter, cursor string) (*models.UsersPage, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.ListUsers(ctx, filter.Proto(cursor))
+	if err != nil {
+		return nil, fmt.Errorf("listUsers api request: %w", err)
+	}
+	return models.UsersPageFromProto(resp), nil
+}
+
+// RegisterWebhook is
+func (api *UsersAPI) RegisterWebhook(url string, eventTypes []models.UserEventType) (*models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.RegisterWebhookRequest{Url: url}
+	for _, t := range eventTypes {
+		opts.EventTypes = append(opts.EventTypes, proto.UserEventType(t))
+	}
+	resp, err := api.UserServiceClient.RegisterWebhook(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("registerWebhook api request: %w", err)
+	}
+	return models.WebhookFromProto(resp), nil
+}
+
+// UnregisterWebhook is
+func (api *UsersAPI) UnregisterWebhook(webhookUUID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	if _, err := api.UserServiceClient.UnregisterWebhook(ctx, &proto.WebhookRequest{WebhookUuid: webhookUUID.Bytes()}); err != nil {
+		return fmt.Errorf("unregisterWebhook api request: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetters is
+func (api *UsersAPI) ListDeadLetters(webhookUUID uuid.UUID) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.ListDeadLetters(ctx, &proto.WebhookRequest{WebhookUuid: webhookUUID.Bytes()})
+	if err != nil {
+		return nil, fmt.Errorf("listDeadLetters api request: %w", err)
+	}
+	deliveries := make([]*models.WebhookDelivery, 0, len(resp.Deliveries))
+	for _, d := range resp.Deliveries {
+		deliveries = append(deliveries, models.WebhookDeliveryFromProto(d))
+	}
+	return deliveries, nil
+}
+
+// ListAuditEvents is
+func (api *UsersAPI) ListAuditEvents(filter models.AuditFilter, cursor string) (*models.AuditPage, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.ListAuditEvents(ctx, filter.Proto(cursor))
+	if err != nil {
+		return nil, fmt.Errorf("listAuditEvents api request: %w", err)
+	}
+	return models.AuditPageFromProto(resp), nil
+}
+
+// RegisterServiceClient is
+func (api *UsersAPI) RegisterServiceClient(name string, scopes []string) (*models.ServiceClient, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.RegisterServiceClient(ctx, &proto.RegisterServiceClientRequest{Name: name, Scopes: scopes})
+	if err != nil {
+		return nil, fmt.Errorf("registerServiceClient api request: %w", err)
+	}
+	return models.ServiceClientFromProto(resp), nil
+}
+
+// DeleteServiceClient is
+func (api *UsersAPI) DeleteServiceClient(clientID string) error {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	if _, err := api.UserServiceClient.DeleteServiceClient(ctx, &proto.ServiceClientRequest{ClientId: clientID}); err != nil {
+		return fmt.Errorf("deleteServiceClient api request: %w", err)
 	}
 	return nil
 }
 
+// ClientCredentialsToken is
+func (api *UsersAPI) ClientCredentialsToken(clientID string, secret []byte, scopes []string) (*models.ServiceToken, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	return api.clientCredentialsToken(ctx, clientID, secret, scopes)
+}
+
+func (api *UsersAPI) clientCredentialsToken(ctx context.Context, clientID string, secret []byte, scopes []string) (*models.ServiceToken, error) {
+	opts := &proto.ClientCredentialsRequest{
+		ClientId:     clientID,
+		ClientSecret: secret,
+		Scopes:       scopes,
+	}
+	resp, err := api.UserServiceClient.ClientCredentialsToken(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("clientCredentialsToken api request: %w", err)
+	}
+	return models.ServiceTokenFromProto(resp), nil
+}
+
+// SignInExternal is
+func (api *UsersAPI) SignInExternal(provider, idToken, nonce string) ([]byte, error) {
+	if nonce == "" {
+		return nil, fmt.Errorf("signInExternal: %w", models.ErrNonceRequired)
+	}
+
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.ExternalSignInRequest{
+		Provider: provider,
+		IdToken:  idToken,
+		Nonce:    nonce,
+	}
+	resp, err := api.UserServiceClient.SignInExternal(ctx, opts)
+	if err != nil {
+		if se, ok := models.AccountStatusErrorFromError(err); ok {
+			return nil, fmt.Errorf("signInExternal: %w", se)
+		}
+		if status.Code(err) == codes.NotFound {
+			return nil, fmt.Errorf("signInExternal api request: %w: %w", models.ErrIdentityNotLinked, err)
+		}
+		return nil, fmt.Errorf("signInExternal api request: %w", err)
+	}
+	return resp.Token, nil
+}
+
+// LinkIdentity is
+func (api *UsersAPI) LinkIdentity(userUUID uuid.UUID, provider, idToken, nonce string) (*models.ExternalIdentity, error) {
+	if nonce == "" {
+		return nil, fmt.Errorf("linkIdentity: %w", models.ErrNonceRequired)
+	}
+
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.LinkIdentityRequest{
+		UserUuid: userUUID.Bytes(),
+		Provider: provider,
+		IdToken:  idToken,
+		Nonce:    nonce,
+	}
+	resp, err := api.UserServiceClient.LinkIdentity(ctx, opts)
+	if err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return nil, fmt.Errorf("linkIdentity api request: %w: %w", models.ErrIdentityAlreadyLinked, err)
+		}
+		return nil, fmt.Errorf("linkIdentity api request: %w", err)
+	}
+	return models.ExternalIdentityFromProto(resp), nil
+}
+
+// UnlinkIdentity is
+func (api *UsersAPI) UnlinkIdentity(userUUID uuid.UUID, provider string) error {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	opts := &proto.UnlinkIdentityRequest{UserUuid: userUUID.Bytes(), Provider: provider}
+	if _, err := api.UserServiceClient.UnlinkIdentity(ctx, opts); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("unlinkIdentity api request: %w: %w", models.ErrIdentityNotLinked, err)
+		}
+		return fmt.Errorf("unlinkIdentity api request: %w", err)
+	}
+	return nil
+}
+
+// ListIdentities is
+func (api *UsersAPI) ListIdentities(userUUID uuid.UUID) ([]*models.ExternalIdentity, error) {
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+	defer cancel()
+
+	resp, err := api.UserServiceClient.ListIdentities(ctx, &proto.UserRequest{UserUuid: userUUID.Bytes()})
+	if err != nil {
+		return nil, fmt.Errorf("listIdentities api request: %w", err)
+	}
+	identities := make([]*models.ExternalIdentity, 0, len(resp.Identities))
+	for _, i := range resp.Identities {
+		identities = append(identities, models.ExternalIdentityFromProto(i))
+	}
+	return identities, nil
+}
+
 func (api *UsersAPI) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
+	// a loader set with WithLoader batches the lookups of its request
+	if l := LoaderFromContext(api.context()); l != nil {
+		ctx, cancel := context.WithTimeout(api.context(), api.timeout)
+		defer cancel()
+		return l.Load(ctx, userUUID)
+	}
+
 	opts := &proto.UserGetter{
 		Getter: &proto.UserGetter_UserUuid{
 			UserUuid: userUUID.Bytes(),
@@ -166,12 +683,25 @@ func (api *UsersAPI) UserByUUID(userUUID uuid.UUID) (*models.User, error) {
 	return api.getUser(opts)
 }
 
+// UserByEmail is
+func (api *UsersAPI) UserByEmail(email string) (*models.User, error) {
+	opts := &proto.UserGetter{
+		Getter: &proto.UserGetter_Email{
+			Email: email,
+		},
+	}
+	return api.getUser(opts)
+}
+
 func (api *UsersAPI) getUser(opts *proto.UserGetter) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	ctx, cancel := context.WithTimeout(api.context(), api.timeout)
 	defer cancel()
 
 	resp, err := api.UserServiceClient.UserBy(ctx, opts)
 	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, fmt.Errorf("userapi request failed: %w: %w", models.ErrUserNotFound, err)
+		}
 		return nil, fmt.Errorf("userapi request failed: %w", err)
 	}
 
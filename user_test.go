@@ -0,0 +1,116 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testServer is an in-process user service, the func fields replace the default answers
+type testServer struct {
+	proto.UnimplementedUserServiceServer
+
+	calls atomic.Int64
+
+	userBy       func(context.Context, *proto.UserGetter) (*proto.User, error)
+	usersByUUIDs func(context.Context, *proto.UsersByUUIDsRequest) (*proto.UsersByUUIDsResponse, error)
+}
+
+func (s *testServer) UserBy(ctx context.Context, req *proto.UserGetter) (*proto.User, error) {
+	s.calls.Add(1)
+	if s.userBy != nil {
+		return s.userBy(ctx, req)
+	}
+	return &proto.User{UserUuid: req.GetUserUuid(), Email: "user@example.com"}, nil
+}
+
+func (s *testServer) UsersByUUIDs(ctx context.Context, req *proto.UsersByUUIDsRequest) (*proto.UsersByUUIDsResponse, error) {
+	s.calls.Add(1)
+	if s.usersByUUIDs != nil {
+		return s.usersByUUIDs(ctx, req)
+	}
+	resp := &proto.UsersByUUIDsResponse{}
+	for _, id := range req.UserUuids {
+		resp.Users = append(resp.Users, &proto.User{UserUuid: id, Email: "user@example.com"})
+	}
+	return resp, nil
+}
+
+// startTestServer serves srv and a health service on a local port until the test ends
+func startTestServer(t *testing.T, srv proto.UserServiceServer) (string, *health.Server) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterUserServiceServer(s, srv)
+	hs := health.NewServer()
+	hs.SetServingStatus(healthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String(), hs
+}
+
+// checkAuthServer answers CheckAuth with a user of status, or with err
+type checkAuthServer struct {
+	testServer
+	status proto.UserStatus
+	err    error
+}
+
+func (s *checkAuthServer) CheckAuth(context.Context, *proto.TokenRequest) (*proto.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &proto.User{UserUuid: uuid.Must(uuid.NewV4()).Bytes(), Email: "jane@example.com", Status: s.status}, nil
+}
+
+func TestCheckAuthRejectsInactiveUsers(t *testing.T) {
+	tests := []struct {
+		name string
+		srv  *checkAuthServer
+		want error
+	}{
+		{"active", &checkAuthServer{status: proto.UserStatus_USER_STATUS_ACTIVE}, nil},
+		// a server answering with the user instead of an error
+		{"deactivated user", &checkAuthServer{status: proto.UserStatus_USER_STATUS_DEACTIVATED}, models.ErrUserDeactivated},
+		{"deleted user", &checkAuthServer{status: proto.UserStatus_USER_STATUS_DELETED}, models.ErrUserDeleted},
+		{"deactivated error", &checkAuthServer{err: &models.AccountStatusError{Status: models.StatusDeactivated}}, models.ErrUserDeactivated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := startTestServer(t, tt.srv)
+			api, err := NewClient(addr, WithTimeout(2*time.Second))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			defer api.Close()
+
+			u, err := api.CheckAuth([]byte("token"))
+			if tt.want == nil {
+				if err != nil || u.Status != models.StatusActive {
+					t.Fatalf("CheckAuth = %v, %v", u, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || u != nil {
+				t.Fatalf("CheckAuth = %v, %v, want %v", u, err, tt.want)
+			}
+			if _, ok := models.AccountStatusErrorFromError(err); !ok {
+				t.Fatalf("CheckAuth error %v is not an AccountStatusError", err)
+			}
+		})
+	}
+}
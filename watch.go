@@ -0,0 +1,137 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	watchMinBackoff = 100 * time.Millisecond
+	watchMaxBackoff = 10 * time.Second
+)
+
+// UserWatch is a WatchUsers subscription that reconnects until its context is done
+// or the server refuses it for good
+type UserWatch struct {
+	events chan *models.UserEvent
+	cursor atomic.Uint64
+
+	mu  sync.Mutex
+	err error
+}
+
+// Events are closed when the watch stops, see Err
+func (w *UserWatch) Events() <-chan *models.UserEvent {
+	return w.events
+}
+
+// Cursor is the sequence of the last event received from Events,
+// persist it to resume a later Watch without gaps
+func (w *UserWatch) Cursor() uint64 {
+	return w.cursor.Load()
+}
+
+// Err is the reason the watch stopped, valid after Events is closed
+func (w *UserWatch) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Watch streams user events after fromCursor. Broken streams are reopened
+// from the last event received by the caller, so no event is lost or repeated.
+func (api *UsersAPI) Watch(ctx context.Context, fromCursor uint64) *UserWatch {
+	w := &UserWatch{events: make(chan *models.UserEvent)}
+	w.cursor.Store(fromCursor)
+	go api.watch(ctx, w)
+	return w
+}
+
+func (api *UsersAPI) watch(ctx context.Context, w *UserWatch) {
+	defer close(w.events)
+
+	backoff := watchMinBackoff
+	for {
+		received, err := api.watchOnce(ctx, w)
+		if ctx.Err() != nil {
+			w.stop(ctx.Err())
+			return
+		}
+		if isTerminalWatchError(err) {
+			w.stop(fmt.Errorf("watchUsers from %d: %w", w.Cursor(), err))
+			return
+		}
+		if received {
+			backoff = watchMinBackoff
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			w.stop(ctx.Err())
+			return
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// isTerminalWatchError reports errors a reconnect cannot fix
+func isTerminalWatchError(err error) bool {
+	switch status.Code(err) {
+	case codes.OutOfRange, codes.InvalidArgument, codes.Unimplemented,
+		codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+// watchOnce reads one stream until it breaks and reports whether any event was delivered
+func (api *UsersAPI) watchOnce(ctx context.Context, w *UserWatch) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := api.UserServiceClient.WatchUsers(ctx, &proto.WatchUsersRequest{FromSequence: w.Cursor()})
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		pb, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return received, err
+		}
+		if pb.Sequence <= w.Cursor() {
+			// replayed by the server after a reconnect
+			continue
+		}
+
+		select {
+		case w.events <- models.UserEventFromProto(pb):
+			w.cursor.Store(pb.Sequence)
+			received = true
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+}
+
+func (w *UserWatch) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	proto "github.com/garden-raccoon/user-pkg/protocols/user"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type deniedWatchServer struct {
+	testServer
+	code codes.Code
+}
+
+func (s *deniedWatchServer) WatchUsers(*proto.WatchUsersRequest, grpc.ServerStreamingServer[proto.UserEvent]) error {
+	return status.Error(s.code, "watch refused")
+}
+
+func TestWatchStopsOnTerminalErrors(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unimplemented, codes.PermissionDenied, codes.Unauthenticated} {
+		t.Run(code.String(), func(t *testing.T) {
+			addr, _ := startTestServer(t, &deniedWatchServer{code: code})
+			api, err := NewClient(addr, WithTimeout(2*time.Second))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			defer api.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			w := api.Watch(ctx, 0)
+			for range w.Events() {
+				t.Fatal("unexpected event")
+			}
+			if ctx.Err() != nil {
+				t.Fatal("watch kept reconnecting until the context was done")
+			}
+			if got := status.Code(w.Err()); got != code {
+				t.Fatalf("Err code = %s, want %s: %v", got, code, w.Err())
+			}
+		})
+	}
+}
+
+// flakyWatchServer serves the events 1 to watchEvents. Its first stream
+// breaks after 3 events, the second fails at once and the third replays
+// the event before FromSequence, the way a server resuming from a
+// snapshot may do.
+type flakyWatchServer struct {
+	testServer
+
+	mu    sync.Mutex
+	froms []uint64
+	times []time.Time
+}
+
+const watchEvents = 6
+
+func (s *flakyWatchServer) WatchUsers(req *proto.WatchUsersRequest, stream grpc.ServerStreamingServer[proto.UserEvent]) error {
+	s.mu.Lock()
+	attempt := len(s.froms)
+	s.froms = append(s.froms, req.FromSequence)
+	s.times = append(s.times, time.Now())
+	s.mu.Unlock()
+
+	first, last := req.FromSequence+1, uint64(watchEvents)
+	switch attempt {
+	case 0:
+		last = 3
+	case 1:
+		return status.Error(codes.Unavailable, "restarting")
+	default:
+		first = max(req.FromSequence, 1)
+	}
+	for seq := first; seq <= last; seq++ {
+		if err := stream.Send(&proto.UserEvent{Sequence: seq, Type: proto.UserEventType_USER_EVENT_TYPE_CREATED}); err != nil {
+			return err
+		}
+	}
+	if attempt == 0 {
+		return status.Error(codes.Unavailable, "connection reset")
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func TestWatchResumesAfterTransientErrors(t *testing.T) {
+	srv := &flakyWatchServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr, WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	w := api.Watch(ctx, 0)
+
+	var seqs []uint64
+	for e := range w.Events() {
+		seqs = append(seqs, e.Sequence)
+		if len(seqs) == watchEvents {
+			cancel()
+		}
+	}
+	if !errors.Is(w.Err(), context.Canceled) {
+		t.Fatalf("Err = %v, want context.Canceled", w.Err())
+	}
+	if len(seqs) != watchEvents {
+		t.Fatalf("events = %v, want 1 to %d", seqs, watchEvents)
+	}
+	for i, seq := range seqs {
+		if seq != uint64(i+1) {
+			t.Fatalf("events = %v, want 1 to %d once each in order", seqs, watchEvents)
+		}
+	}
+	if w.Cursor() != watchEvents {
+		t.Fatalf("Cursor = %d, want %d", w.Cursor(), watchEvents)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.froms) != 3 || srv.froms[0] != 0 || srv.froms[1] != 3 || srv.froms[2] != 3 {
+		t.Fatalf("FromSequence of the streams = %v, want [0 3 3]", srv.froms)
+	}
+	// the backoff is reset by the events of the first stream and doubled
+	// after the second, which delivered none
+	if gap := srv.times[1].Sub(srv.times[0]); gap < watchMinBackoff {
+		t.Fatalf("reconnected after %s, want at least %s", gap, watchMinBackoff)
+	}
+	if gap := srv.times[2].Sub(srv.times[1]); gap < 2*watchMinBackoff {
+		t.Fatalf("reconnected after %s, want at least %s", gap, 2*watchMinBackoff)
+	}
+}
+
+func TestWatchStartsFromCursor(t *testing.T) {
+	srv := &flakyWatchServer{}
+	addr, _ := startTestServer(t, srv)
+	api, err := NewClient(addr, WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer api.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w := api.Watch(ctx, 1)
+	e, ok := <-w.Events()
+	if !ok {
+		t.Fatalf("Events closed: %v", w.Err())
+	}
+	if e.Sequence != 2 {
+		t.Fatalf("first event = %d, want 2", e.Sequence)
+	}
+	if w.Cursor() != 2 {
+		t.Fatalf("Cursor = %d, want 2", w.Cursor())
+	}
+}
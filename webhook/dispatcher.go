@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultMinBackoff   = time.Second
+	defaultMaxBackoff   = time.Hour
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultHTTPTimeout  = 10 * time.Second
+)
+
+// Payload is the JSON body posted to webhooks. It is a contract with the
+// receivers, fields may be added but never renamed or removed.
+type Payload struct {
+	DeliveryUUID uuid.UUID    `json:"delivery_uuid"`
+	Sequence     uint64       `json:"sequence"`
+	Type         string       `json:"type"`
+	OccurredAt   time.Time    `json:"occurred_at"`
+	User         *PayloadUser `json:"user,omitempty"`
+}
+
+// PayloadUser is the user of a Payload, decoupled from models.User so
+// changes of the Go model do not change the body
+type PayloadUser struct {
+	UserUUID  uuid.UUID  `json:"user_uuid"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	UserType  int        `json:"user_type"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Avatar    string     `json:"avatar"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+func payloadUser(u *models.User) *PayloadUser {
+	if u == nil {
+		return nil
+	}
+	p := &PayloadUser{
+		UserUUID:  u.UserUUID,
+		Username:  u.Username,
+		Email:     u.Email,
+		UserType:  u.UserType,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Avatar:    u.Avatar,
+		Status:    u.Status.String(),
+		CreatedAt: u.CreatedAt,
+	}
+	if !u.PurgeAt.IsZero() {
+		purgeAt := u.PurgeAt
+		p.PurgeAt = &purgeAt
+	}
+	return p
+}
+
+// Dispatcher posts due outbox deliveries to their webhooks
+type Dispatcher struct {
+	outbox       Outbox
+	client       *http.Client
+	maxAttempts  int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	now          func() time.Time
+}
+
+// Option configures Dispatcher
+type Option func(d *Dispatcher)
+
+// WithHTTPClient sets the client used for deliveries. The default client
+// only connects to public addresses and does not follow redirects.
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = c
+	}
+}
+
+// WithMaxAttempts sets how many attempts are made before a delivery is dead lettered
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay after the first failure and its upper bound,
+// the delay doubles after every failed attempt
+func WithBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.minBackoff = minDelay
+		d.maxBackoff = maxDelay
+	}
+}
+
+// WithPollInterval sets how often Run looks for due deliveries
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.pollInterval = interval
+	}
+}
+
+// WithClock replaces time.Now
+func WithClock(now func() time.Time) Option {
+	return func(d *Dispatcher) {
+		d.now = now
+	}
+}
+
+// NewDispatcher is
+func NewDispatcher(outbox Outbox, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		outbox:       outbox,
+		client:       newHTTPClient(),
+		maxAttempts:  defaultMaxAttempts,
+		minBackoff:   defaultMinBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// an unavailable outbox is retried on the next tick
+		_, _ = d.DispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue makes one attempt for every due delivery and returns how many
+// succeeded. Webhooks are posted to concurrently, the deliveries of one
+// webhook in order.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	due, err := d.outbox.Due(ctx, d.now(), d.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("webhook outbox due: %w", err)
+	}
+
+	byWebhook := make(map[uuid.UUID][]Pending)
+	for _, p := range due {
+		byWebhook[p.Webhook.WebhookUUID] = append(byWebhook[p.Webhook.WebhookUUID], p)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+		firstErr  error
+	)
+	for _, pending := range byWebhook {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, p := range pending {
+				ok, err := d.attempt(ctx, p)
+				mu.Lock()
+				if ok {
+					delivered++
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				if !ok {
+					// later events of the webhook wait for this one
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return delivered, firstErr
+}
+
+// attempt posts one delivery, records the outcome in the outbox and
+// reports whether it was delivered, only outbox and ctx errors are returned
+func (d *Dispatcher) attempt(ctx context.Context, p Pending) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	id := p.Delivery.DeliveryUUID
+	sendErr := d.send(ctx, p)
+	if err := ctx.Err(); sendErr != nil && err != nil {
+		// a post cut short by shutdown is not a failed attempt
+		return false, err
+	}
+	if sendErr == nil {
+		if err := d.outbox.Delivered(ctx, id); err != nil {
+			return false, fmt.Errorf("webhook outbox delivered: %w", err)
+		}
+		return true, nil
+	}
+
+	attempts := p.Delivery.Attempts + 1
+	if attempts >= d.maxAttempts {
+		if err := d.outbox.DeadLetter(ctx, id, sendErr.Error()); err != nil {
+			return false, fmt.Errorf("webhook outbox dead letter: %w", err)
+		}
+		return false, nil
+	}
+	if err := d.outbox.Retry(ctx, id, d.now().Add(d.backoff(attempts)), sendErr.Error()); err != nil {
+		return false, fmt.Errorf("webhook outbox retry: %w", err)
+	}
+	return false, nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, p Pending) error {
+	event := p.Delivery.Event
+	body, err := json.Marshal(Payload{
+		DeliveryUUID: p.Delivery.DeliveryUUID,
+		Sequence:     event.Sequence,
+		Type:         event.Type.String(),
+		OccurredAt:   event.OccurredAt,
+		User:         payloadUser(event.User),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, p.Delivery.DeliveryUUID.String())
+	req.Header.Set(SignatureHeader, Sign(p.Webhook.Secret, body, d.now()))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// backoff is the delay before attempt number attempts+1
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.minBackoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxBackoff)
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/garden-raccoon/user-pkg/models"
+	"github.com/gofrs/uuid"
+)
+
+// secretSize is the size of generated signing secrets
+const secretSize = 32
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Pending is a delivery together with the webhook it goes to
+type Pending struct {
+	Delivery models.WebhookDelivery
+	Webhook  models.Webhook
+}
+
+// Outbox is the delivery queue. The user store implements it on its own
+// database: Enqueue is called inside the transaction that changes the user,
+// so an event is never lost or sent for a change that was rolled back, and
+// pending deliveries survive a restart. How the store passes its transaction
+// to Enqueue is up to the store, e.g. in ctx.
+//
+// Deliveries of one webhook are delivered in event order, Due must not
+// return a delivery while an older one of the same webhook is waiting for
+// its retry.
+type Outbox interface {
+	// Enqueue queues the event for every webhook subscribed to its type
+	Enqueue(ctx context.Context, event *models.UserEvent) error
+	// Due returns up to limit deliveries whose next attempt is not after now
+	Due(ctx context.Context, now time.Time, limit int) ([]Pending, error)
+	// Delivered removes the delivery
+	Delivered(ctx context.Context, deliveryUUID uuid.UUID) error
+	// Retry schedules the next attempt and counts the failed one
+	Retry(ctx context.Context, deliveryUUID uuid.UUID, next time.Time, lastErr string) error
+	// DeadLetter moves the delivery to the dead-letter list
+	DeadLetter(ctx context.Context, deliveryUUID uuid.UUID, lastErr string) error
+}
+
+// MemoryOutbox is an Outbox and webhook registry kept in memory, for tests.
+// It is not transactional and pending deliveries are lost on restart.
+type MemoryOutbox struct {
+	mu          sync.Mutex
+	webhooks    map[uuid.UUID]models.Webhook
+	queue       map[uuid.UUID]*queued
+	deadLetters []models.WebhookDelivery
+}
+
+type queued struct {
+	delivery models.WebhookDelivery
+	next     time.Time
+}
+
+// NewMemoryOutbox is
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{
+		webhooks: make(map[uuid.UUID]models.Webhook),
+		queue:    make(map[uuid.UUID]*queued),
+	}
+}
+
+// Register adds a webhook with a new signing secret, the url must pass ValidateURL
+func (o *MemoryOutbox) Register(url string, eventTypes []models.UserEventType) (*models.Webhook, error) {
+	if err := ValidateURL(url); err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("webhook uuid: %w", err)
+	}
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("webhook secret: %w", err)
+	}
+	w := models.Webhook{WebhookUUID: id, URL: url, EventTypes: eventTypes, Secret: secret}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.webhooks[id] = w
+	return &w, nil
+}
+
+// Unregister removes the webhook and its queued deliveries
+func (o *MemoryOutbox) Unregister(webhookUUID uuid.UUID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.webhooks[webhookUUID]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(o.webhooks, webhookUUID)
+	for id, q := range o.queue {
+		if q.delivery.WebhookUUID == webhookUUID {
+			delete(o.queue, id)
+		}
+	}
+	return nil
+}
+
+// Enqueue queues the event for every webhook subscribed to its type.
+// The memory outbox has no transaction, it is queued right away.
+func (o *MemoryOutbox) Enqueue(_ context.Context, event *models.UserEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, w := range o.webhooks {
+		if !w.Wants(event.Type) {
+			continue
+		}
+		id, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("delivery uuid: %w", err)
+		}
+		o.queue[id] = &queued{delivery: models.WebhookDelivery{
+			DeliveryUUID: id,
+			WebhookUUID:  w.WebhookUUID,
+			Event:        event,
+		}}
+	}
+	return nil
+}
+
+// DeadLetters returns the dead lettered deliveries of the webhook
+func (o *MemoryOutbox) DeadLetters(webhookUUID uuid.UUID) []models.WebhookDelivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var out []models.WebhookDelivery
+	for _, d := range o.deadLetters {
+		if d.WebhookUUID == webhookUUID {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Due is, a webhook whose oldest delivery waits for a retry gets none
+func (o *MemoryOutbox) Due(_ context.Context, now time.Time, limit int) ([]Pending, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	all := make([]*queued, 0, len(o.queue))
+	for _, q := range o.queue {
+		all = append(all, q)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].delivery.Event.Sequence < all[j].delivery.Event.Sequence
+	})
+
+	var due []*queued
+	blocked := make(map[uuid.UUID]bool)
+	for _, q := range all {
+		id := q.delivery.WebhookUUID
+		if blocked[id] {
+			continue
+		}
+		if q.next.After(now) {
+			blocked[id] = true
+			continue
+		}
+		due = append(due, q)
+	}
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]Pending, 0, len(due))
+	for _, q := range due {
+		out = append(out, Pending{Delivery: q.delivery, Webhook: o.webhooks[q.delivery.WebhookUUID]})
+	}
+	return out, nil
+}
+
+// Delivered is
+func (o *MemoryOutbox) Delivered(_ context.Context, deliveryUUID uuid.UUID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.queue, deliveryUUID)
+	return nil
+}
+
+// Retry is
+func (o *MemoryOutbox) Retry(_ context.Context, deliveryUUID uuid.UUID, next time.Time, lastErr string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if q, ok := o.queue[deliveryUUID]; ok {
+		q.delivery.Attempts++
+		q.delivery.LastError = lastErr
+		q.next = next
+	}
+	return nil
+}
+
+// DeadLetter is
+func (o *MemoryOutbox) DeadLetter(_ context.Context, deliveryUUID uuid.UUID, lastErr string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	q, ok := o.queue[deliveryUUID]
+	if !ok {
+		return nil
+	}
+	delete(o.queue, deliveryUUID)
+	q.delivery.Attempts++
+	q.delivery.LastError = lastErr
+	o.deadLetters = append(o.deadLetters, q.delivery)
+	return nil
+}
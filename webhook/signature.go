@@ -0,0 +1,72 @@
+// Package webhook delivers user events to HTTP endpoints.
+//
+// The user store writes a delivery for every subscribed webhook into its
+// Outbox in the same transaction as the user change, a Dispatcher then
+// posts the signed payloads and retries failed deliveries with
+// exponential backoff until they succeed or are dead lettered.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>"
+	SignatureHeader = "X-User-Signature"
+	// DeliveryHeader carries the delivery uuid, receivers use it to drop duplicates
+	DeliveryHeader = "X-User-Delivery"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature expired")
+)
+
+// Sign returns the SignatureHeader value for body sent at t.
+// The timestamp is part of the signed data so a payload cannot be replayed later.
+func Sign(secret, body []byte, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+// Verify checks a SignatureHeader value against body,
+// signatures older than tolerance are rejected, zero tolerance disables the check
+func Verify(secret, body []byte, header string, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, ts, body)) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)) > tolerance {
+		return fmt.Errorf("%w: signed at %s", ErrSignatureExpired, time.Unix(unix, 0).UTC())
+	}
+	return nil
+}
+
+func mac(secret []byte, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}